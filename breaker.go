@@ -0,0 +1,232 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	mysql "github.com/go-sql-driver/mysql"
+)
+
+// CircuitState is a state of the client circuit breaker.
+//
+type CircuitState int
+
+const (
+	CircuitClosed   CircuitState = iota // calls are allowed
+	CircuitOpen                         // calls fail fast with ErrCircuitOpen
+	CircuitHalfOpen                     // a limited number of probe calls is allowed
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+
+	return "unknown"
+}
+
+func (s CircuitState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// IsConnectionError checks if the error means the database can't be reached, like network errors or a broken connection.
+// Wrapped errors, like QueryError, are unwrapped.
+//
+func IsConnectionError(err error) bool {
+	// context errors implement net.Error, but they mean the caller gave up, not the database
+	if err == nil || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1040, // too many connections
+			1053: // server shutdown in progress
+			return true
+		}
+	}
+
+	return false
+}
+
+// breaker opens when the rate of connection errors in a window is reached, closes after enough successful probes.
+type breaker struct {
+	mu sync.Mutex
+
+	enabled     bool
+	errorRate   float64
+	minRequests int64
+	window      time.Duration
+	openTimeout time.Duration
+	probes      int
+	cfg         *Config
+
+	state       CircuitState
+	windowStart time.Time
+	requests    int64
+	failures    int64
+	changedAt   time.Time
+	probing     int // probe calls allowed in half-open state
+	succeeded   int // successful probe calls in half-open state
+	transitions int64
+}
+
+func newBreaker() *breaker {
+	return &breaker{}
+}
+
+func (b *breaker) configure(cfg *Config) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.enabled = cfg.CircuitBreaker
+	b.errorRate = cfg.CircuitBreakerErrorRate
+	b.minRequests = cfg.CircuitBreakerMinRequests
+	b.window = cfg.CircuitBreakerWindow
+	b.openTimeout = cfg.CircuitBreakerOpenTimeout
+	b.probes = cfg.CircuitBreakerProbes
+	b.cfg = cfg
+
+	if b.probes < 1 {
+		b.probes = 1
+	}
+
+	if b.errorRate <= 0 {
+		b.errorRate = NewDefaultConfig().CircuitBreakerErrorRate
+	}
+
+	if !b.enabled && b.state != CircuitClosed {
+		b.setState(context.Background(), CircuitClosed)
+	}
+}
+
+// allow returns ErrCircuitOpen if the call can't be made. It returns true if the call is a probe
+// in half-open state, its result has to be passed to done.
+func (b *breaker) allow(ctx context.Context) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.enabled {
+		return false, nil
+	}
+
+	expired := time.Now().Sub(b.changedAt) >= b.openTimeout
+
+	switch b.state {
+	case CircuitOpen:
+		if !expired {
+			return false, ErrCircuitOpen
+		}
+		b.setState(ctx, CircuitHalfOpen)
+		b.probing++
+		return true, nil
+
+	case CircuitHalfOpen:
+		if expired {
+			// probes didn't finish in time
+			b.probing, b.succeeded = 0, 0
+			b.changedAt = time.Now()
+		}
+
+		if b.probing >= b.probes {
+			return false, ErrCircuitOpen
+		}
+		b.probing++
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// done records a result of an allowed call, probe is the value returned by allow. Only database errors wrapped
+// with QueryError are taken into account. A probe rejected before reaching the database is given back,
+// so another call can probe. In half-open state only results of probes are taken into account.
+func (b *breaker) done(ctx context.Context, probe bool, err error) {
+	var qErr *QueryError
+	rejected := err != nil && !errors.As(err, &qErr)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.enabled {
+		return
+	}
+
+	if rejected {
+		if probe && b.state == CircuitHalfOpen && b.probing > 0 {
+			b.probing--
+		}
+		return
+	}
+
+	failed := IsConnectionError(err)
+
+	switch b.state {
+	case CircuitHalfOpen:
+		if !probe {
+			return
+		}
+
+		if failed {
+			b.setState(ctx, CircuitOpen)
+			return
+		}
+
+		if b.succeeded++; b.succeeded >= b.probes {
+			b.setState(ctx, CircuitClosed)
+		}
+
+	case CircuitClosed:
+		if now := time.Now(); now.Sub(b.windowStart) >= b.window {
+			b.windowStart = now
+			b.requests, b.failures = 0, 0
+		}
+
+		b.requests++
+		if failed {
+			b.failures++
+		}
+
+		if b.failures > 0 && b.requests >= b.minRequests && float64(b.failures)/float64(b.requests) >= b.errorRate {
+			b.setState(ctx, CircuitOpen)
+		}
+	}
+}
+
+// setState changes the state, b.mu must be held.
+func (b *breaker) setState(ctx context.Context, state CircuitState) {
+	logEvent(ctx, b.cfg, LevelWarning, LogEvent{Message: "circuit breaker: " + b.state.String() + " -> " + state.String()})
+
+	b.state = state
+	b.transitions++
+	b.probing, b.succeeded = 0, 0
+	b.requests, b.failures = 0, 0
+	b.windowStart = time.Now()
+	b.changedAt = time.Now()
+}
+
+func (b *breaker) stats() (CircuitState, int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state, b.transitions
+}
@@ -0,0 +1,163 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestBreaker(probes int) *breaker {
+	cfg := NewDefaultConfig()
+	cfg.CircuitBreaker = true
+	cfg.CircuitBreakerErrorRate = 0.5
+	cfg.CircuitBreakerMinRequests = 4
+	cfg.CircuitBreakerWindow = time.Minute
+	cfg.CircuitBreakerOpenTimeout = time.Millisecond * 20
+	cfg.CircuitBreakerProbes = probes
+
+	b := newBreaker()
+	b.configure(cfg)
+	return b
+}
+
+// openBreaker makes the breaker open and waits until probes are allowed.
+func openBreaker(t *testing.T, b *breaker) {
+	ctx := context.Background()
+	connErr := newQueryError(b.cfg, driver.ErrBadConn, "SELECT 1", nil, 1, 0, 0)
+
+	for i := 0; i < 4; i++ {
+		probe, err := b.allow(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.done(ctx, probe, connErr)
+	}
+
+	if state, _ := b.stats(); state != CircuitOpen {
+		t.Fatalf("expected open circuit, got %s", state)
+	}
+	if _, err := b.allow(ctx); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+
+	time.Sleep(b.openTimeout)
+}
+
+func TestBreakerOpensOnConnectionErrors(t *testing.T) {
+	tests := []struct {
+		err    error
+		opened bool
+	}{
+		{newQueryError(NewDefaultConfig(), driver.ErrBadConn, "SELECT 1", nil, 1, 0, 0), true},
+		{newQueryError(NewDefaultConfig(), errors.New("syntax"), "SELECT 1", nil, 1, 0, 0), false},
+		{ErrQueueTimeout, false},
+	}
+
+	for _, test := range tests {
+		b := newTestBreaker(1)
+		for i := 0; i < 4; i++ {
+			probe, _ := b.allow(context.Background())
+			b.done(context.Background(), probe, test.err)
+		}
+
+		if state, _ := b.stats(); (state == CircuitOpen) != test.opened {
+			t.Errorf("%v: expected opened %v, got %s", test.err, test.opened, state)
+		}
+	}
+}
+
+func TestBreakerClosesAfterSuccessfulProbes(t *testing.T) {
+	ctx := context.Background()
+	b := newTestBreaker(2)
+	openBreaker(t, b)
+
+	first, err := b.allow(ctx)
+	if err != nil || !first {
+		t.Fatalf("expected a probe, got %v %v", first, err)
+	}
+	second, err := b.allow(ctx)
+	if err != nil || !second {
+		t.Fatalf("expected a probe, got %v %v", second, err)
+	}
+	if _, err := b.allow(ctx); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen when all probes are in flight, got %v", err)
+	}
+
+	// a call allowed before the circuit opened doesn't count as a probe
+	b.done(ctx, false, nil)
+	b.done(ctx, first, nil)
+	if state, _ := b.stats(); state != CircuitHalfOpen {
+		t.Fatalf("expected half-open circuit, got %s", state)
+	}
+
+	b.done(ctx, second, nil)
+	if state, _ := b.stats(); state != CircuitClosed {
+		t.Fatalf("expected closed circuit, got %s", state)
+	}
+}
+
+func TestBreakerReopensOnFailedProbe(t *testing.T) {
+	ctx := context.Background()
+	b := newTestBreaker(2)
+	openBreaker(t, b)
+
+	probe, _ := b.allow(ctx)
+	b.done(ctx, probe, newQueryError(b.cfg, driver.ErrBadConn, "SELECT 1", nil, 1, 0, 0))
+
+	if state, _ := b.stats(); state != CircuitOpen {
+		t.Fatalf("expected open circuit, got %s", state)
+	}
+}
+
+func TestBreakerGivesBackRejectedProbes(t *testing.T) {
+	ctx := context.Background()
+	b := newTestBreaker(1)
+	openBreaker(t, b)
+
+	probe, err := b.allow(ctx)
+	if err != nil || !probe {
+		t.Fatalf("expected a probe, got %v %v", probe, err)
+	}
+
+	// the probe is rejected by the relay before reaching the database
+	b.done(ctx, probe, ErrQueueTimeout)
+
+	probe, err = b.allow(ctx)
+	if err != nil || !probe {
+		t.Fatalf("expected the probe to be given back, got %v %v", probe, err)
+	}
+
+	b.done(ctx, probe, nil)
+	if state, _ := b.stats(); state != CircuitClosed {
+		t.Fatalf("expected closed circuit, got %s", state)
+	}
+}
+
+func TestBreakerWithZeroErrorRate(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := NewDefaultConfig()
+	cfg.CircuitBreaker = true
+	cfg.CircuitBreakerErrorRate = 0
+	cfg.CircuitBreakerMinRequests = 1
+
+	b := newBreaker()
+	b.configure(cfg)
+
+	for i := 0; i < 10; i++ {
+		probe, err := b.allow(ctx)
+		if err != nil {
+			t.Fatalf("call %d: expected the call to be allowed, got %v", i, err)
+		}
+		b.done(ctx, probe, nil)
+	}
+
+	if state, _ := b.stats(); state != CircuitClosed {
+		t.Errorf("expected closed circuit after successful calls, got %s", state)
+	}
+	if b.errorRate != NewDefaultConfig().CircuitBreakerErrorRate {
+		t.Errorf("expected the default error rate, got %v", b.errorRate)
+	}
+}
@@ -12,18 +12,27 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	mysql "github.com/go-sql-driver/mysql"
 )
 
+const drainInterval = time.Millisecond * 10
+
 type Client struct {
-	db      *sql.DB
+	f       *failover
 	replica *Client
-	config  *Config
+	role    Role
+	config  atomic.Value // *Config, replaced by SetConfig
 	s       *stats
+	k       *killer
+	tn      *tenants
+	b       *breaker
+	cm      *commenter
+	closed  int32
 
 	mu sync.Mutex
 	r  *relay
@@ -37,32 +46,85 @@ type Client struct {
 //  time_zone - set connection timezone
 //  multiStatements - allow multiple statements
 func NewClient(dsn string) (*Client, error) {
-	db, err := sql.Open("mysql", dsn)
+	connector, err := mysql.MySQLDriver{}.OpenConnector(dsn)
 	if err != nil {
 		return nil, err
 	}
 
+	return newClient([]driver.Connector{connector}), nil
+}
+
+// newClient opens a pool for every endpoint, and a separate one used to kill its queries.
+func newClient(connectors []driver.Connector) *Client {
 	cfg := NewDefaultConfig()
 	s := newStats()
 
-	c := &Client{db, nil, cfg, s, sync.Mutex{}, &relay{}}
+	endpoints := make([]*sql.DB, len(connectors))
+	for i, connector := range connectors {
+		endpoints[i] = sql.OpenDB(connector)
+	}
+
+	c := &Client{newFailover(endpoints), nil, RolePrimary, atomic.Value{}, s, newKiller(endpoints, connectors), newTenants(), newBreaker(), newCommenter(), 0, sync.Mutex{}, newRelay()}
 	c.SetConfig(cfg)
 
-	return c, nil
+	return c
 }
 
 func (c *Client) SetReplica(replica *Client) {
+	replica.role = RoleReplica
 	c.replica = replica
 }
 
-// SetConfig applies config passed in cfg.
+// Replica returns the replica set with SetReplica, nil if there is none.
+//
+func (c *Client) Replica() *Client {
+	return c.replica
+}
+
+// SetConfig applies config passed in cfg. It's safe to call while queries are in progress: calls in progress
+// and open transactions finish with the previous config, connections waiting in queue and held by calls
+// are kept when MaxOpenConns changes.
+// The config is copied, changes made to cfg afterwards don't affect the client.
 //
 func (c *Client) SetConfig(cfg *Config) {
-	c.config = cfg
-	c.db.SetMaxIdleConns(cfg.MaxIdleConns)
-	c.db.SetMaxOpenConns(cfg.MaxOpenConns)
-	c.db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
-	c.r.setRelaySize(cfg.MaxOpenConns * 10)
+	cfg = cfg.clone()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.config.Store(cfg)
+	for _, db := range c.f.endpoints {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+		db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+	c.r.setWeights(cfg.PriorityWeights)
+	c.r.setMaxWait(cfg.MaxQueueWait)
+	c.r.setRelaySize(relaySize(cfg))
+	c.s.limiter.configure(cfg)
+	c.b.configure(cfg)
+	c.s.digests.setLimit(cfg.DigestLimit)
+	c.s.slowLog.configure(cfg)
+}
+
+// relaySize returns the relay capacity. With priority lanes or MaxQueueWait calls wait for a connection
+// in the relay, so it's sized to the pool. Otherwise the relay only bounds calls queued in the pool.
+func relaySize(cfg *Config) int {
+	if len(cfg.PriorityWeights) > 0 || cfg.MaxQueueWait > 0 {
+		return cfg.MaxOpenConns
+	}
+
+	return cfg.MaxOpenConns * 10
+}
+
+// Config returns a copy of the config applied with SetConfig.
+//
+func (c *Client) Config() *Config {
+	return c.cfg().clone()
+}
+
+func (c *Client) cfg() *Config {
+	return c.config.Load().(*Config)
 }
 
 // Begin opens or returns trasaction found in the context.
@@ -73,36 +135,65 @@ func (c *Client) Begin(ctx context.Context, opts *sql.TxOptions) (*Transaction,
 		return c.replica.Begin(ctx, opts)
 	}
 
-	var err error
+	cfg := c.cfg()
+
+	var (
+		err   error
+		probe bool
+	)
 
 	if tx, ok := ctx.Value("tx").(*Transaction); ok {
 		return tx, nil
 	}
 
+	startedAt := time.Now()
+	sample := &QueryStats{Query: "BEGIN", Operation: OperationBegin, Priority: priorityFromCtx(ctx), Role: c.role, StartedAt: startedAt}
+	ctx, span := startSpan(ctx, cfg.Tracer, OperationBegin, "BEGIN")
+	defer func() {
+		c.s.sample(ctx, sample.finish(err))
+		span.End(sample)
+		logSample(ctx, cfg, sample)
+	}()
+
 	defer func(e *error) {
+		c.b.done(ctx, probe, *e)
+
 		if *e != nil {
 			atomic.AddInt64(c.s.inProgressQueries, -1)
+			c.tn.end(tenantFromCtx(ctx), *e)
 		}
 	}(&err)
 
-	if err := c.limitReached(); err != nil {
+	if probe, err = c.limitReached(ctx); err != nil {
 		return nil, err
 	}
 
-	queueTime := c.r.start()
+	queueTime, err := c.r.start(ctx)
+	sample.QueueTime = queueTime
+	if err != nil {
+		return nil, err
+	}
 	defer c.r.conditionalEnd(&err)
 
 	start := time.Now()
-	tx, err := c.db.BeginTx(ctx, opts)
+	db := c.primary()
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil && c.failover(ctx, db, err) {
+		db = c.primary()
+		tx, err = db.BeginTx(ctx, opts)
+	}
 	queryTime := time.Now().Sub(start)
 
-	c.s.sample(&QueryStats{"BEGIN", queryTime, queueTime, 0})
-	logger.FromCtx(ctx).Tag("mysql").Debug("BEGIN", queryTime, 0)
+	err = newQueryError(cfg, err, "BEGIN", nil, 1, queueTime, queryTime)
+	sample.ExecutionTime = queryTime
+	sample.Attempts = 1
 	if err != nil {
 		return nil, err
 	}
 
-	return newTransaction(tx, c.s, c.config, c.r), nil
+	t := newTransaction(tx, db, c.f, c.s, cfg, c.r, c.tn, c.b, c.cm, tenantFromCtx(ctx), c.role)
+	sample.TransactionID = t.id
+	return t, nil
 }
 
 /*
@@ -136,49 +227,92 @@ func (c *Client) MultiCall(ctx context.Context, procedure string, args ...interf
 Exec prepares a query that does not return any data except metadata and executes it, for example inserts, updates, etc..
 */
 func (c *Client) Exec(ctx context.Context, query string, args ...interface{}) (*Meta, error) {
+	cfg := c.cfg()
 
 	var (
-		i      int = 1
-		err    error
-		result sql.Result
-		cancel func()
+		i          int = 1
+		attempts   int
+		err        error
+		result     sql.Result
+		cancel     func()
+		q          queryer
+		release    func()
+		failedOver bool
+		probe      bool
 	)
 
 	defer func(err *error) {
 		atomic.AddInt64(c.s.inProgressQueries, -1)
+		c.tn.end(tenantFromCtx(ctx), *err)
+		c.b.done(ctx, probe, *err)
 
 		if *err == nil {
 			atomic.AddInt64(c.s.totalSuccessQueries, 1)
 		} else {
-			atomic.AddInt64(c.s.totalFailedQueries, 1)
-			logger.FromCtx(ctx).Tag("mysql").Error(*err, query)
+			c.s.failed(*err)
 		}
 	}(&err)
 
-	if err = c.limitReached(); err != nil {
+	startedAt := time.Now()
+	sample := &QueryStats{Query: redactQuery(cfg, query), Operation: OperationExec, Priority: priorityFromCtx(ctx), Args: len(args), Role: c.role, StartedAt: startedAt, statement: query, args: args}
+	ctx, span := startSpan(ctx, cfg.Tracer, OperationExec, sample.Query)
+	// the sample is finished once and published before it's passed to the span, so subscribers never see it changing
+	defer func() {
+		c.s.sample(ctx, sample.finish(err))
+		span.End(sample)
+		logSample(ctx, cfg, sample)
+	}()
+
+	if probe, err = c.limitReached(ctx); err != nil {
 		return nil, err
 	}
 
-	if c.config.RetryOnDeadlock {
-		i += c.config.RetryOnDeadlockCount
+	if cfg.RetryOnDeadlock {
+		i += cfg.RetryOnDeadlockCount
 	}
 
-	if c.config.Timeout > 0 {
-		ctx, cancel = context.WithTimeout(ctx, c.config.Timeout)
-		defer cancel()
-	}
+	ctx, cancel = withTimeout(ctx, cfg)
+	defer cancel()
 
-	queueTime := c.r.start()
+	queueTime, err := c.r.start(ctx)
+	sample.QueueTime = queueTime
+	if err != nil {
+		return nil, err
+	}
 	defer c.r.end()
 
+	statement := c.cm.comment(ctx, cfg, query)
+	db := c.primary()
+	if q, release, err = c.acquire(ctx, db, sample.Query); err != nil {
+		err = newQueryError(cfg, err, query, args, 0, queueTime, 0)
+		return nil, err
+	}
+	defer func() { release() }()
+
 	start := time.Now()
 
 	for ; i > 0; i-- {
-		result, err = c.db.ExecContext(ctx, query, args...)
+		attempts++
+		result, err = q.ExecContext(ctx, statement, args...)
 
 		if IsErrorCode(err, ErrMySQLDeadlock) {
-			logger.FromCtx(ctx).Tag("mysql").Warning("deadlock", query)
-			time.Sleep(c.config.RetryOnDeadlockDelay)
+			logEvent(ctx, cfg, LevelWarning, LogEvent{Message: "deadlock", Query: sample.Query, Operation: OperationExec, Attempts: attempts, Role: c.role, Err: newQueryError(cfg, err, query, args, attempts, queueTime, 0)})
+			time.Sleep(cfg.RetryOnDeadlockDelay)
+			continue
+		}
+
+		if !failedOver && c.failover(ctx, db, err) {
+			// the statement wasn't applied, it's retried once on the new endpoint
+			failedOver = true
+			release()
+
+			db = c.primary()
+			if q, release, err = c.acquire(ctx, db, sample.Query); err != nil {
+				release = func() {}
+				break
+			}
+
+			i++
 			continue
 		}
 
@@ -186,15 +320,18 @@ func (c *Client) Exec(ctx context.Context, query string, args ...interface{}) (*
 	}
 
 	queryTime := time.Now().Sub(start)
-	c.s.sample(&QueryStats{query, queryTime, queueTime, 0})
+	sample.ExecutionTime = queryTime
+	sample.Attempts = attempts
+
 	if err != nil {
+		err = newQueryError(cfg, err, query, args, attempts, queueTime, queryTime)
 		return nil, err
 	}
 
 	meta := newMeta(result)
 	meta.QueryTime = queryTime
+	sample.RowsAffected = meta.RowsAffected
 
-	logger.FromCtx(ctx).Tag("mysql").Debug(query, meta.QueryTime)
 	return meta, nil
 }
 
@@ -203,56 +340,89 @@ func (c *Client) Query(ctx context.Context, query string, args ...interface{}) (
 		return c.replica.Query(ctx, query, args...)
 	}
 
+	cfg := c.cfg()
+
 	var (
-		i      int = 1
-		err    error
-		rows   *sql.Rows
-		cancel func()
+		i        int = 1
+		attempts int
+		err      error
+		rows     *sql.Rows
+		cancel   func()
+		q        queryer
+		release  func()
+		probe    bool
 	)
 
 	defer func(err *error) {
 		atomic.AddInt64(c.s.inProgressQueries, -1)
+		c.tn.end(tenantFromCtx(ctx), *err)
+		c.b.done(ctx, probe, *err)
+
 		if *err == nil {
 			atomic.AddInt64(c.s.totalSuccessQueries, 1)
 		} else {
-			atomic.AddInt64(c.s.totalFailedQueries, 1)
-			logger.FromCtx(ctx).Tag("mysql").Error(*err, query)
+			c.s.failed(*err)
 		}
 	}(&err)
 
-	if err = c.limitReached(); err != nil {
+	startedAt := time.Now()
+	sample := &QueryStats{Query: redactQuery(cfg, query), Operation: OperationQuery, Priority: priorityFromCtx(ctx), Args: len(args), Role: c.role, StartedAt: startedAt, statement: query, args: args}
+	ctx, span := startSpan(ctx, cfg.Tracer, OperationQuery, sample.Query)
+	defer func() {
+		c.s.sample(ctx, sample.finish(err))
+		span.End(sample)
+		logSample(ctx, cfg, sample)
+	}()
+
+	if probe, err = c.limitReached(ctx); err != nil {
 		return nil, err
 	}
 
-	if c.config.RetryOnDeadlock {
-		i += c.config.RetryOnDeadlockCount
+	if cfg.RetryOnDeadlock {
+		i += cfg.RetryOnDeadlockCount
 	}
 
-	if c.config.Timeout > 0 {
-		ctx, cancel = context.WithTimeout(ctx, c.config.Timeout)
-		defer cancel()
-	}
+	ctx, cancel = withTimeout(ctx, cfg)
+	defer cancel()
 
-	queueTime := c.r.start()
+	queueTime, err := c.r.start(ctx)
+	sample.QueueTime = queueTime
+	if err != nil {
+		return nil, err
+	}
 	defer c.r.end()
 
+	db := c.primary()
+	if q, release, err = c.acquire(ctx, db, sample.Query); err != nil {
+		err = newQueryError(cfg, err, query, args, 0, queueTime, 0)
+		return nil, err
+	}
+	defer func() { release() }()
+
+	// the hint is set after waiting in the queue, so the server doesn't run the query longer than the caller waits
+	hintedQuery := c.cm.comment(ctx, cfg, maxExecutionTime(ctx, cfg, query))
+
 	start := time.Now()
 
 	for ; i > 0; i-- {
-		rows, err = c.db.QueryContext(ctx, query, args...)
+		attempts++
+		rows, err = q.QueryContext(ctx, hintedQuery, args...)
 
 		if IsErrorCode(err, ErrMySQLDeadlock) {
-			time.Sleep(c.config.RetryOnDeadlockDelay)
+			time.Sleep(cfg.RetryOnDeadlockDelay)
 			continue
 		}
 
+		c.failover(ctx, db, err)
 		break
 	}
 
 	queryTime := time.Now().Sub(start)
-	c.s.sample(&QueryStats{query, queryTime, queueTime, 0})
+	sample.ExecutionTime = queryTime
+	sample.Attempts = attempts
 
 	if err != nil {
+		err = newQueryError(cfg, err, query, args, attempts, queueTime, queryTime)
 		return nil, err
 	}
 
@@ -263,7 +433,7 @@ func (c *Client) Query(ctx context.Context, query string, args ...interface{}) (
 	}
 
 	results.QueryTime = queryTime
-	logger.FromCtx(ctx).Tag("mysql").Debug(query, queryTime, queueTime)
+	sample.RowsReturned = int64(results.Count())
 	return results, nil
 }
 
@@ -278,57 +448,89 @@ MultiQuery executes multiple queries at once and returns as MultiResults.
  fmt.Println(mRes.Results[1].Rows[0].GetElementByName("version").Element.NullString.String)
 */
 func (c *Client) MultiQuery(ctx context.Context, query string, args ...interface{}) (*MultiResults, error) {
+	cfg := c.cfg()
 
 	var (
-		i      int = 1
-		err    error
-		rows   *sql.Rows
-		cancel func()
+		i        int = 1
+		attempts int
+		err      error
+		rows     *sql.Rows
+		cancel   func()
+		q        queryer
+		release  func()
+		probe    bool
 	)
 
 	defer func(err *error) {
 		atomic.AddInt64(c.s.inProgressQueries, -1)
+		c.tn.end(tenantFromCtx(ctx), *err)
+		c.b.done(ctx, probe, *err)
+
 		if *err == nil {
 			atomic.AddInt64(c.s.totalSuccessQueries, 1)
 		} else {
-			atomic.AddInt64(c.s.totalFailedQueries, 1)
-			logger.FromCtx(ctx).Tag("mysql").Error(*err, query)
+			c.s.failed(*err)
 		}
 	}(&err)
 
-	if err = c.limitReached(); err != nil {
+	startedAt := time.Now()
+	sample := &QueryStats{Query: redactQuery(cfg, query), Operation: OperationQuery, Priority: priorityFromCtx(ctx), Args: len(args), Role: c.role, StartedAt: startedAt, statement: query, args: args}
+	ctx, span := startSpan(ctx, cfg.Tracer, OperationQuery, sample.Query)
+	defer func() {
+		c.s.sample(ctx, sample.finish(err))
+		span.End(sample)
+		logSample(ctx, cfg, sample)
+	}()
+
+	if probe, err = c.limitReached(ctx); err != nil {
 		return nil, err
 	}
 
-	if c.config.RetryOnDeadlock {
-		i += c.config.RetryOnDeadlockCount
+	if cfg.RetryOnDeadlock {
+		i += cfg.RetryOnDeadlockCount
 	}
 
-	if c.config.Timeout > 0 {
-		ctx, cancel = context.WithTimeout(ctx, c.config.Timeout)
-		defer cancel()
-	}
+	ctx, cancel = withTimeout(ctx, cfg)
+	defer cancel()
 
-	queueTime := c.r.start()
+	queueTime, err := c.r.start(ctx)
+	sample.QueueTime = queueTime
+	if err != nil {
+		return nil, err
+	}
 	defer c.r.end()
 
+	db := c.primary()
+	if q, release, err = c.acquire(ctx, db, sample.Query); err != nil {
+		err = newQueryError(cfg, err, query, args, 0, queueTime, 0)
+		return nil, err
+	}
+	defer func() { release() }()
+
+	// the hint is set after waiting in the queue, so the server doesn't run the query longer than the caller waits
+	hintedQuery := c.cm.comment(ctx, cfg, maxExecutionTime(ctx, cfg, query))
+
 	start := time.Now()
 
 	for ; i > 0; i-- {
-		rows, err = c.db.QueryContext(ctx, query, args...)
+		attempts++
+		rows, err = q.QueryContext(ctx, hintedQuery, args...)
 
 		if IsErrorCode(err, ErrMySQLDeadlock) {
-			time.Sleep(c.config.RetryOnDeadlockDelay)
+			time.Sleep(cfg.RetryOnDeadlockDelay)
 			continue
 		}
 
+		c.failover(ctx, db, err)
 		break
 	}
 
 	queryTime := time.Now().Sub(start)
-	c.s.sample(&QueryStats{query, queryTime, queueTime, 0})
+	sample.ExecutionTime = queryTime
+	sample.Attempts = attempts
 
 	if err != nil {
+		err = newQueryError(cfg, err, query, args, attempts, queueTime, queryTime)
 		return nil, err
 	}
 
@@ -340,19 +542,19 @@ func (c *Client) MultiQuery(ctx context.Context, query string, args ...interface
 	}
 
 	for {
-		results, err := newResultsFromSqlRows(rows)
-		if err != nil {
+		var results *Results
+		if results, err = newResultsFromSqlRows(rows); err != nil {
 			return nil, err
 		}
 		results.QueryTime = queryTime
 		multiResults.Results = append(multiResults.Results, results)
+		sample.RowsReturned += int64(results.Count())
 
 		if rows.NextResultSet() == false {
 			break
 		}
 	}
 
-	logger.FromCtx(ctx).Tag("mysql").Debug(query, queryTime, queueTime)
 	return multiResults, nil
 }
 
@@ -381,30 +583,113 @@ func (c *Client) Stats() *Stats {
 	defer c.mu.Unlock()
 
 	s := &Stats{
-		DBStats:             c.db.Stats(),
+		DBStats:             c.f.db().Stats(),
 		InProgressQueries:   atomic.LoadInt64(c.s.inProgressQueries),
 		TotalSuccessQueries: atomic.LoadInt64(c.s.totalSuccessQueries),
 		TotalFailedQueries:  atomic.LoadInt64(c.s.totalFailedQueries),
+		TotalKilledQueries:  atomic.LoadInt64(c.k.kills),
+		Priorities:          c.r.stats(),
+		Tenants:             c.tn.stats(),
+		QueueWait:           c.r.queueWait.snapshot(),
+		QueueLength:         c.r.queueLength.snapshot(),
+		ConcurrencyLimit:    c.s.limiter.current(),
+		ActiveEndpoint:      c.f.activeIndex(),
+		Latency:             c.s.latencyStats(),
+		Errors:              c.s.errorStats(),
+		DroppedSamples:      atomic.LoadInt64(c.s.droppedSamples),
 	}
+	s.CircuitState, s.CircuitTransitions = c.b.stats()
 
 	return s
 }
 
+// Close stops accepting new calls, they fail with ErrClientClosed. It waits until calls in progress and open
+// transactions are finished or ctx is done, then closes all endpoints and the replica.
+// It returns ctx error if calls were still in progress.
+//
+func (c *Client) Close(ctx context.Context) error {
+	atomic.StoreInt32(&c.closed, 1)
+	if c.replica != nil {
+		// calls delegated to the replica are rejected too, it's drained when it's closed
+		atomic.StoreInt32(&c.replica.closed, 1)
+	}
+
+	err := c.drain(ctx)
+
+	for _, db := range c.f.endpoints {
+		if closeErr := db.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	c.k.close()
+
+	if c.replica != nil {
+		if closeErr := c.replica.Close(ctx); err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+func (c *Client) drain(ctx context.Context) error {
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+
+	for atomic.LoadInt64(c.s.inProgressQueries) > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// TopQueries returns up to n query digests in the given order, grouped by Fingerprint.
+// If n <= 0 then all digests are returned.
+//
+//  for _, d := range client.TopQueries(10, mysql.ByTotalExecutionTime) {
+//    fmt.Println(d.Fingerprint, d.Count, d.TotalExecutionTime)
+//  }
+func (c *Client) TopQueries(n int, order DigestOrder) []QueryDigest {
+	return c.s.digests.top(n, order)
+}
+
+// SamplesChan returns a channel of samples buffered up to 100 samples, new samples are dropped when it's full.
+// Use Subscribe for a different buffer size or overflow policy.
+//
 func (c *Client) SamplesChan() chan *QueryStats {
-	return c.s.sampleChan
+	return c.s.sampleSub.c
 }
 
-func (c *Client) limitReached() error {
+// limitReached checks if the call can be made. It returns true if the call is a circuit breaker probe.
+func (c *Client) limitReached(ctx context.Context) (bool, error) {
+	// both counters are decreased by the caller, even if the call is rejected
 	atomic.AddInt64(c.s.inProgressQueries, 1)
+	if err := c.tn.start(tenantFromCtx(ctx), c.cfg().MaxTenantQueries); err != nil {
+		return false, err
+	}
+
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return false, ErrClientClosed
+	}
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	internalStats := c.db.Stats()
+	internalStats := c.f.db().Stats()
+
+	inProgress := atomic.LoadInt64(c.s.inProgressQueries)
 
-	if atomic.LoadInt64(c.s.inProgressQueries)-int64(internalStats.MaxOpenConnections) > c.config.MaxQueuedQueries {
-		return ErrQueueOverloaded
+	if inProgress-int64(internalStats.MaxOpenConnections) > c.cfg().MaxQueuedQueries {
+		return false, ErrQueueOverloaded
 	}
 
-	return nil
+	if limit := c.s.limiter.current(); limit > 0 && inProgress > limit {
+		return false, ErrQueueOverloaded
+	}
+
+	return c.b.allow(ctx)
 }
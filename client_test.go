@@ -0,0 +1,90 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestSampleIsNotModifiedAfterPublishing(t *testing.T) {
+	d := &fakeDriver{handler: func(query string) error {
+		return errors.New("failed")
+	}}
+	c := newFakeClient(t, d, nil)
+
+	sub := c.Subscribe(100, OverflowBlock)
+	defer sub.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 10; i++ {
+			s := <-sub.C()
+			if s.Err == nil || s.ErrorNumber != 0 {
+				t.Errorf("sample %d: expected an error without a mysql number, got %v %d", i, s.Err, s.ErrorNumber)
+			}
+		}
+	}()
+
+	for i := 0; i < 10; i++ {
+		if _, err := c.Exec(context.Background(), "UPDATE `foo` SET `bar` = 1"); err == nil {
+			t.Fatal("expected an error")
+		}
+	}
+
+	wg.Wait()
+}
+
+func TestRejectedCallIsPublished(t *testing.T) {
+	d := &fakeDriver{}
+	c := newFakeClient(t, d, nil)
+
+	sub := c.Subscribe(1, OverflowDrop)
+	defer sub.Close()
+
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Exec(context.Background(), "UPDATE `foo` SET `bar` = 1"); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("expected ErrClientClosed, got %v", err)
+	}
+
+	s := <-sub.C()
+	if !errors.Is(s.Err, ErrClientClosed) || s.Attempts != 0 {
+		t.Errorf("expected a rejected sample without attempts, got %v with %d attempts", s.Err, s.Attempts)
+	}
+	if len(d.executed()) != 0 {
+		t.Errorf("expected no statements, got %v", d.executed())
+	}
+}
+
+func TestCloseRejectsCallsDelegatedToReplica(t *testing.T) {
+	primary, replica := &fakeDriver{}, &fakeDriver{}
+	c := newFakeClient(t, primary, nil)
+	c.SetReplica(newFakeClient(t, replica, nil))
+
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithTenant(context.Background(), "foo")
+	if _, err := c.Query(ctx, "SELECT 1"); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("expected ErrClientClosed, got %v", err)
+	}
+	if _, err := c.Exec(ctx, "UPDATE `foo` SET `bar` = 1"); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("expected ErrClientClosed, got %v", err)
+	}
+	if executed := append(primary.executed(), replica.executed()...); len(executed) != 0 {
+		t.Errorf("expected no statements, got %v", executed)
+	}
+
+	for _, client := range []*Client{c, c.Replica()} {
+		if s := client.Stats().Tenants["foo"]; s.InProgressQueries != 0 {
+			t.Errorf("%s: expected no tenant queries in progress, got %d", client.role, s.InProgressQueries)
+		}
+	}
+}
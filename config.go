@@ -1,6 +1,10 @@
 package mysql
 
-import "time"
+import (
+	"io"
+	"regexp"
+	"time"
+)
 
 type Config struct {
 
@@ -13,12 +17,120 @@ type Config struct {
 	// Time between retries
 	RetryOnDeadlockDelay time.Duration
 
-	// Query timeout set in context, if d <= 0 then timeout isn't set
+	// Query timeout set in context, if d <= 0 then timeout isn't set.
+	// An earlier deadline set by the caller is respected, the timeout can be overridden per call with WithQueryOptions.
 	Timeout time.Duration
 
+	// Enforce timeout on the server side by adding MAX_EXECUTION_TIME hint to SELECT queries
+	ServerSideTimeout bool
+
+	// Issue KILL QUERY when a context is cancelled while the query is still running. KILL QUERY is sent over
+	// a separate pool of 2 connections per endpoint, which is not limited by MaxOpenConns.
+	// It costs one additional roundtrip per query.
+	KillQueryOnCancel bool
+
 	// Limit of waiting calls for connection
 	MaxQueuedQueries int64
 
+	// Adjust the limit of in-flight calls from observed execution times, calls above the limit fail with ErrQueueOverloaded.
+	// The limit grows while queries are faster than AdaptiveLimitLatency and is reduced by 10% when they are slower.
+	AdaptiveLimit bool
+
+	// Target execution time of the adaptive limit, if d <= 0 then the default target is used
+	AdaptiveLimitLatency time.Duration
+
+	// Bounds of the adaptive limit, the limit starts from AdaptiveLimitMax
+	AdaptiveLimitMin int64
+	AdaptiveLimitMax int64
+
+	// Fail fast with ErrCircuitOpen when the rate of connection errors reaches CircuitBreakerErrorRate.
+	// After CircuitBreakerOpenTimeout CircuitBreakerProbes calls are let through, the circuit closes when all of them succeed.
+	CircuitBreaker bool
+
+	// Rate of connection errors opening the circuit, from 0 to 1, if rate <= 0 then the default rate is used
+	CircuitBreakerErrorRate float64
+
+	// Minimal number of calls in a window required to open the circuit
+	CircuitBreakerMinRequests int64
+
+	// Window in which the error rate is measured
+	CircuitBreakerWindow time.Duration
+
+	// Time the circuit stays open before probe calls are let through
+	CircuitBreakerOpenTimeout time.Duration
+
+	// Number of probe calls in half-open state
+	CircuitBreakerProbes int
+
+	// Decides if writes return to a preferred endpoint after a failover, see NewFailoverClient
+	FailbackPolicy FailbackPolicy
+
+	// Time between checks of preferred endpoints with FailbackPreferred policy
+	FailbackInterval time.Duration
+
+	// Timeout of @@read_only checks made during failover
+	FailoverCheckTimeout time.Duration
+
+	// Limit of time spent waiting for connection, if d <= 0 then calls wait until their context is done
+	MaxQueueWait time.Duration
+
+	// Limit of in-flight calls (running and waiting for connection) of a tenant set with WithTenant, if n <= 0 then there is no limit
+	MaxTenantQueries int64
+
+	// Weights of priority lanes used when queries wait for connection, set with WithPriority, e.g.
+	// {"interactive": 4, "background": 1}. Lanes not found in the map get weight 1. With weights or MaxQueueWait set
+	// at most MaxOpenConns calls are admitted at once, the others wait in the lanes, if the map is empty then
+	// priorities aren't used and calls wait for connection in the pool
+	PriorityWeights map[Priority]int
+
+	// Queries slower than the threshold are logged with Logger.Warning, if d <= 0 then slow queries aren't logged
+	SlowQueryThreshold time.Duration
+
+	// Fraction of slow queries logged, from 0 to 1, if rate <= 0 then all slow queries are logged
+	SlowQuerySampleRate float64
+
+	// Optional writer of slow queries in MySQL slow log format, like a file
+	SlowLogWriter io.Writer
+
+	// Limit of query fingerprints aggregated for TopQueries, if n <= 0 then queries aren't aggregated
+	DigestLimit int
+
+	// Columns whose arg values are logged and reported in QueryError, other args are reported as their types.
+	// Args are matched with columns in comparisons, like `id = ?`, and in INSERT column lists.
+	LogArgs []string
+
+	// Replaces string and number literals in query text with ? in logs, QueryStats, spans and QueryError
+	RedactLiterals bool
+
+	// Replaces matches of the patterns in query text with ? in logs, QueryStats, spans and QueryError, like emails
+	RedactPatterns []*regexp.Regexp
+
+	// Logger of the client and its transactions, if nil then the logger set with SetLogger is used
+	Logger Logger
+
+	// Minimum level of logged events, calls made with WithVerboseLogging context are always logged
+	LogLevel LogLevel
+
+	// Fraction of successful calls logged at debug level, from 0 to 1, if rate <= 0 then all of them are logged
+	LogDebugSampleRate float64
+
+	// Successful calls slower than the threshold, including queue time, are logged at warning level,
+	// unless they're already logged as slow queries, if d <= 0 then they're logged at debug level
+	LogLatencyThreshold time.Duration
+
+	// Optional tracer starting spans for Begin, statements, Commit and Rollback
+	Tracer Tracer
+
+	// Appends sqlcommenter comments with ServiceName, route and tenant, set with WithRoute and WithTenant, to statements
+	SQLComment bool
+
+	// Name of the service added to sql comments as application
+	ServiceName string
+
+	// Adds traceparent of the statement span to sql comments, Tracer has to implement TraceParent(ctx) string.
+	// Every statement text becomes unique, so it's disabled by default.
+	SQLCommentTraceParent bool
+
 	// If MaxIdleConns is greater than 0 and the new MaxOpenConns is less than MaxIdleConns, then MaxIdleConns will be reduced to match the new MaxOpenConns limit.
 	// If n <= 0, then there is no limit on the number of open connections. The default is 0 (unlimited).
 	MaxOpenConns int
@@ -37,9 +149,49 @@ func NewDefaultConfig() *Config {
 		RetryOnDeadlockDelay: time.Millisecond * 10,
 		Timeout:              time.Second * 10,
 		MaxQueuedQueries:     10000,
+		AdaptiveLimitLatency: time.Millisecond * 100,
+		AdaptiveLimitMin:     20,
+		AdaptiveLimitMax:     1000,
+
+		CircuitBreakerErrorRate:   0.5,
+		CircuitBreakerMinRequests: 20,
+		CircuitBreakerWindow:      time.Second * 10,
+		CircuitBreakerOpenTimeout: time.Second * 5,
+		CircuitBreakerProbes:      3,
+
+		FailbackPolicy:       FailbackNever,
+		FailbackInterval:     time.Second * 30,
+		FailoverCheckTimeout: time.Second,
+
+		SlowQuerySampleRate: 1,
+		LogDebugSampleRate:  1,
+		DigestLimit:         1000,
 
 		MaxOpenConns:    20,
 		MaxIdleConns:    20,
 		ConnMaxLifetime: time.Second * 60,
 	}
 }
+
+// clone returns a copy of the config, maps and slices aren't shared with c. Empty ones are set to nil.
+func (c *Config) clone() *Config {
+	cfg := *c
+	cfg.PriorityWeights, cfg.LogArgs, cfg.RedactPatterns = nil, nil, nil
+
+	if len(c.PriorityWeights) > 0 {
+		cfg.PriorityWeights = make(map[Priority]int, len(c.PriorityWeights))
+		for p, w := range c.PriorityWeights {
+			cfg.PriorityWeights[p] = w
+		}
+	}
+
+	if len(c.LogArgs) > 0 {
+		cfg.LogArgs = append([]string(nil), c.LogArgs...)
+	}
+
+	if len(c.RedactPatterns) > 0 {
+		cfg.RedactPatterns = append([]*regexp.Regexp(nil), c.RedactPatterns...)
+	}
+
+	return &cfg
+}
@@ -0,0 +1,180 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDriver is a database/sql driver executing statements without a database. Every statement waits for delay
+// and returns the error returned by handler, if it's set. SELECT CONNECTION_ID() and KILL statements aren't delayed.
+// SELECT @@read_only returns readOnly.
+type fakeDriver struct {
+	mu       sync.Mutex
+	delay    time.Duration
+	handler  func(query string) error
+	queries  []string
+	conns    uint64
+	readOnly int64
+}
+
+func (d *fakeDriver) run(ctx context.Context, query string) error {
+	d.mu.Lock()
+	d.queries = append(d.queries, query)
+	delay, handler := d.delay, d.handler
+	d.mu.Unlock()
+
+	if delay > 0 && query != connectionIDQuery && !strings.HasPrefix(query, "KILL") {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if handler != nil {
+		return handler(query)
+	}
+
+	return nil
+}
+
+func (d *fakeDriver) executed() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]string(nil), d.queries...)
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return d.Connect(context.Background())
+}
+
+func (d *fakeDriver) Connect(ctx context.Context) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.conns++
+	return &fakeConn{d, d.conns}, nil
+}
+
+func (d *fakeDriver) Driver() driver.Driver {
+	return d
+}
+
+type fakeConn struct {
+	d  *fakeDriver
+	id uint64
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.d.run(ctx, "BEGIN"); err != nil {
+		return nil, err
+	}
+
+	return &fakeTx{c}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.d.run(ctx, query); err != nil {
+		return nil, err
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.d.run(ctx, query); err != nil {
+		return nil, err
+	}
+
+	switch query {
+	case connectionIDQuery:
+		return &fakeRows{[]string{"CONNECTION_ID()"}, [][]driver.Value{{int64(c.id)}}}, nil
+	case "SELECT @@read_only":
+		c.d.mu.Lock()
+		defer c.d.mu.Unlock()
+
+		return &fakeRows{[]string{"@@read_only"}, [][]driver.Value{{c.d.readOnly}}}, nil
+	}
+
+	return &fakeRows{}, nil
+}
+
+type fakeTx struct {
+	c *fakeConn
+}
+
+func (t *fakeTx) Commit() error {
+	return t.c.d.run(context.Background(), "COMMIT")
+}
+
+func (t *fakeTx) Rollback() error {
+	return t.c.d.run(context.Background(), "ROLLBACK")
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// newFakeClient returns a client with a single endpoint backed by the fake driver.
+func newFakeClient(t *testing.T, d *fakeDriver, configure func(cfg *Config)) *Client {
+	return newFakeFailoverClient(t, []*fakeDriver{d}, configure)
+}
+
+// newFakeFailoverClient returns a client with an endpoint backed by every fake driver.
+func newFakeFailoverClient(t *testing.T, ds []*fakeDriver, configure func(cfg *Config)) *Client {
+	connectors := make([]driver.Connector, len(ds))
+	for i, d := range ds {
+		connectors[i] = d
+	}
+
+	c := newClient(connectors)
+
+	cfg := NewDefaultConfig()
+	if configure != nil {
+		configure(cfg)
+	}
+	c.SetConfig(cfg)
+
+	t.Cleanup(func() {
+		c.Close(context.Background())
+	})
+
+	return c
+}
@@ -2,6 +2,10 @@ package mysql
 
 import (
 	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
 
 	mysql "github.com/go-sql-driver/mysql"
 )
@@ -12,23 +16,141 @@ const (
 	ErrMySQLDeadlock   SQLErrorNumber = 1213
 	ErrMySQLDupEntry                  = 1062
 	ErrMySQLCoinstaint                = 1452
+	ErrMySQLReadOnly                  = 1290
 )
 
 var (
-	ErrQueueOverloaded = errors.New("queue overloaded")
-	ErrWrongReference  = errors.New("err wrong reference")
-	ErrRollback        = errors.New("tx rollback")
+	ErrQueueOverloaded  = errors.New("queue overloaded")
+	ErrTenantOverloaded = errors.New("tenant queue overloaded")
+	ErrQueueTimeout     = errors.New("queue wait timeout")
+	ErrCircuitOpen      = errors.New("circuit breaker open")
+	ErrClientClosed     = errors.New("client closed")
+	ErrWrongReference   = errors.New("err wrong reference")
+	ErrRollback         = errors.New("tx rollback")
 )
 
+// matches "Duplicate entry 'value' for key 'key'" (MySQL 5.7) and "Duplicate entry 'value' for key 'table.key'" (MySQL 8.0)
+var dupEntryRegexp = regexp.MustCompile(`(?s)^Duplicate entry '(.*)' for key '(.*)'$`)
+
+// QueryError is returned by Client and Transaction when a statement fails.
+// It carries the failed query with its execution details and unwraps to the driver error.
+//
+//  var qErr *QueryError
+//  if errors.As(err, &qErr) {
+//    log.Println(qErr.Query, qErr.Number, qErr.ExecutionTime)
+//  }
+type QueryError struct {
+	Query         string        // failed query, redacted according to Config.RedactLiterals and Config.RedactPatterns
+	Args          []string      // redacted query arguments, only types are kept unless allowed with Config.LogArgs
+	Number        uint16        // mysql error number, 0 if the error is not a mysql error
+	SQLState      string        // mysql SQLSTATE, empty if not returned by the server
+	Attempts      int           // number of attempts including deadlock retries
+	QueueTime     time.Duration // time spent in queue waiting for connection
+	ExecutionTime time.Duration // time of query execution including retries
+	Err           error         // underlying driver error, its message isn't redacted
+
+	message string // redacted message of Err
+}
+
+func newQueryError(cfg *Config, err error, query string, args []interface{}, attempts int, queueTime, executionTime time.Duration) error {
+	if err == nil {
+		return nil
+	}
+
+	qErr := &QueryError{
+		Query:         redactQuery(cfg, query),
+		Args:          redactArgs(cfg.LogArgs, query, args),
+		Attempts:      attempts,
+		QueueTime:     queueTime,
+		ExecutionTime: executionTime,
+		Err:           err,
+		message:       redactError(cfg, err),
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		qErr.Number = mysqlErr.Number
+		if mysqlErr.SQLState != [5]byte{} {
+			qErr.SQLState = string(mysqlErr.SQLState[:])
+		}
+	}
+
+	return qErr
+}
+
+// Error returns the error message redacted like the query, e.g. the value of a duplicate entry is masked
+// when Config.RedactLiterals is enabled.
+//
+func (e *QueryError) Error() string {
+	message := e.message
+	if message == "" {
+		message = fmt.Sprint(e.Err)
+	}
+
+	return fmt.Sprintf("%s [query: %s, args: %v, attempts: %d, queue time: %s, execution time: %s]",
+		message, e.Query, e.Args, e.Attempts, e.QueueTime, e.ExecutionTime)
+}
+
+// Unwrap returns the underlying driver error, its message isn't redacted.
+//
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// errorNumber returns mysql error number of err, 0 if err is not a mysql error.
+func errorNumber(err error) uint16 {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number
+	}
+
+	return 0
+}
+
 // IsErrorCode checks if the error is one of standard mysql error codes.
+// Wrapped errors, like QueryError, are unwrapped.
 //
 //  if IsErrorCode(err, ErrMySQLDupEntry) {
 //    // handle duplicate entry
 //  }
 func IsErrorCode(err error, no SQLErrorNumber) bool {
-	if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == uint16(no) {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == uint16(no) {
 		return true
 	}
 
 	return false
 }
+
+// DupEntry describes a unique key collision reported with ErrMySQLDupEntry.
+//
+type DupEntry struct {
+	Value string // duplicated value, multi-column values are joined with '-' by the server
+	Key   string // name of the unique key, PRIMARY for a primary key
+	Table string // table name, reported only by MySQL 8.0
+}
+
+// ParseDupEntry returns details of a duplicate entry error. Wrapped errors, like QueryError, are unwrapped.
+// It returns false if the error is not ErrMySQLDupEntry or the message can't be parsed.
+//
+//  if dup, ok := ParseDupEntry(err); ok && dup.Key == "email" {
+//    // handle duplicated email
+//  }
+func ParseDupEntry(err error) (*DupEntry, bool) {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number != ErrMySQLDupEntry {
+		return nil, false
+	}
+
+	matches := dupEntryRegexp.FindStringSubmatch(mysqlErr.Message)
+	if matches == nil {
+		return nil, false
+	}
+
+	dup := &DupEntry{Value: matches[1], Key: matches[2]}
+	if i := strings.LastIndex(dup.Key, "."); i >= 0 {
+		dup.Table, dup.Key = dup.Key[:i], dup.Key[i+1:]
+	}
+
+	return dup, true
+}
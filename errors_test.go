@@ -0,0 +1,103 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	mysql "github.com/go-sql-driver/mysql"
+)
+
+func TestParseDupEntry(t *testing.T) {
+	dupErr := func(message string) error {
+		return &mysql.MySQLError{Number: ErrMySQLDupEntry, Message: message}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		dup  *DupEntry
+	}{
+		{"mysql 5.7", dupErr("Duplicate entry 'a@b.c' for key 'email'"), &DupEntry{Value: "a@b.c", Key: "email"}},
+		{"mysql 8.0", dupErr("Duplicate entry 'a@b.c' for key 'users.email'"), &DupEntry{Value: "a@b.c", Key: "email", Table: "users"}},
+		{"primary key", dupErr("Duplicate entry '1' for key 'users.PRIMARY'"), &DupEntry{Value: "1", Key: "PRIMARY", Table: "users"}},
+		{"multi-column value", dupErr("Duplicate entry '1-2' for key 'user_roles.user_role'"), &DupEntry{Value: "1-2", Key: "user_role", Table: "user_roles"}},
+		{"value containing the separator", dupErr("Duplicate entry 'a' for key 'b' for key 'notes.body'"), &DupEntry{Value: "a' for key 'b", Key: "body", Table: "notes"}},
+		{"multi-line value", dupErr("Duplicate entry 'a\nb' for key 'name'"), &DupEntry{Value: "a\nb", Key: "name"}},
+		{"wrapped in QueryError", newQueryError(NewDefaultConfig(), dupErr("Duplicate entry '1' for key 'PRIMARY'"), "INSERT INTO foo (id) VALUES (?)", []interface{}{1}, 1, 0, 0), &DupEntry{Value: "1", Key: "PRIMARY"}},
+		{"wrapped with fmt", fmt.Errorf("insert: %w", dupErr("Duplicate entry '1' for key 'PRIMARY'")), &DupEntry{Value: "1", Key: "PRIMARY"}},
+		{"other mysql error", &mysql.MySQLError{Number: ErrMySQLCoinstaint, Message: "Duplicate entry '1' for key 'PRIMARY'"}, nil},
+		{"unexpected message", dupErr("Duplicate key"), nil},
+		{"not a mysql error", errors.New("Duplicate entry '1' for key 'PRIMARY'"), nil},
+		{"nil error", nil, nil},
+	}
+
+	for _, test := range tests {
+		dup, ok := ParseDupEntry(test.err)
+		if ok != (test.dup != nil) || !reflect.DeepEqual(dup, test.dup) {
+			t.Errorf("%s: expected %+v, got %+v, %v", test.name, test.dup, dup, ok)
+		}
+	}
+}
+
+func TestQueryErrorUnwrapsToDriverError(t *testing.T) {
+	deadlock := &mysql.MySQLError{Number: uint16(ErrMySQLDeadlock), SQLState: [5]byte{'4', '0', '0', '0', '1'}, Message: "Deadlock found when trying to get lock"}
+	d := &fakeDriver{handler: func(query string) error {
+		return deadlock
+	}}
+	c := newFakeClient(t, d, func(cfg *Config) {
+		cfg.RetryOnDeadlockCount = 2
+		cfg.RetryOnDeadlockDelay = 0
+	})
+
+	for name, call := range map[string]func() error{
+		"exec": func() error {
+			_, err := c.Exec(context.Background(), "UPDATE `foo` SET `bar` = ?", 1)
+			return err
+		},
+		"query": func() error {
+			_, err := c.Query(context.Background(), "SELECT * FROM `foo` WHERE `bar` = ?", 1)
+			return err
+		},
+	} {
+		err := call()
+
+		var qErr *QueryError
+		if !errors.As(err, &qErr) {
+			t.Fatalf("%s: expected a QueryError, got %T", name, err)
+		}
+		if qErr.Number != uint16(ErrMySQLDeadlock) || qErr.SQLState != "40001" || qErr.Attempts != 3 {
+			t.Errorf("%s: expected number 1213, SQLSTATE 40001 and 3 attempts, got %d, %q and %d", name, qErr.Number, qErr.SQLState, qErr.Attempts)
+		}
+
+		var mysqlErr *mysql.MySQLError
+		if !errors.As(err, &mysqlErr) || mysqlErr != deadlock {
+			t.Errorf("%s: expected errors.As to find the driver error, got %v", name, mysqlErr)
+		}
+		if errors.Unwrap(err) != deadlock {
+			t.Errorf("%s: expected Unwrap to return the driver error, got %v", name, errors.Unwrap(err))
+		}
+		if !IsErrorCode(err, ErrMySQLDeadlock) || IsErrorCode(err, ErrMySQLDupEntry) {
+			t.Errorf("%s: expected IsErrorCode to match only the deadlock", name)
+		}
+	}
+}
+
+func TestQueryErrorWithoutMySQLError(t *testing.T) {
+	failed := errors.New("failed")
+	c := newFakeClient(t, &fakeDriver{handler: func(query string) error {
+		return failed
+	}}, nil)
+
+	_, err := c.Exec(context.Background(), "UPDATE `foo` SET `bar` = 1")
+
+	var qErr *QueryError
+	if !errors.As(err, &qErr) {
+		t.Fatalf("expected a QueryError, got %T", err)
+	}
+	if qErr.Number != 0 || qErr.SQLState != "" || qErr.Attempts != 1 || !errors.Is(err, failed) {
+		t.Errorf("expected no mysql number and SQLSTATE after one attempt, got %+v", qErr)
+	}
+}
@@ -0,0 +1,245 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	mysql "github.com/go-sql-driver/mysql"
+)
+
+// FailbackPolicy decides if writes return to a preferred endpoint after a failover.
+//
+type FailbackPolicy int
+
+const (
+	FailbackNever     FailbackPolicy = iota // writes stay on the endpoint they were switched to
+	FailbackPreferred                       // writes return to the first writable endpoint of the list, checked every FailbackInterval
+)
+
+// NewFailoverClient creates a new client for an ordered list of write endpoints, see NewClient for the DSN format.
+// Writes go to the first endpoint. When it fails with a connection error or ErrMySQLReadOnly, writes are switched
+// to the next endpoint with @@read_only = 0. Begin and Exec calls rejected by a read-only or unreachable endpoint
+// are retried once on the new endpoint.
+//
+//  client, err := mysql.NewFailoverClient("user:pass@tcp(primary:3306)/db", "user:pass@tcp(standby:3306)/db")
+func NewFailoverClient(dsns ...string) (*Client, error) {
+	if len(dsns) == 0 {
+		return nil, errors.New("no endpoints")
+	}
+
+	connectors := make([]driver.Connector, len(dsns))
+	for i, dsn := range dsns {
+		connector, err := mysql.MySQLDriver{}.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		connectors[i] = connector
+	}
+
+	return newClient(connectors), nil
+}
+
+type failover struct {
+	mu         sync.RWMutex
+	endpoints  []*sql.DB
+	active     int
+	recovering chan struct{} // closed when the running recovery check is finished, nil if there is none
+
+	switching    sync.Mutex // serializes endpoint checks
+	failingBack  int32
+	lastFailback int64 // unix nano
+}
+
+func newFailover(endpoints []*sql.DB) *failover {
+	return &failover{endpoints: endpoints}
+}
+
+// db returns the endpoint writes go to.
+func (f *failover) db() *sql.DB {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.endpoints[f.active]
+}
+
+func (f *failover) activeIndex() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.active
+}
+
+func (f *failover) activate(ctx context.Context, cfg *Config, i int) {
+	f.mu.Lock()
+	from := f.active
+	f.active = i
+	f.mu.Unlock()
+
+	logEvent(ctx, cfg, LevelWarning, LogEvent{Message: fmt.Sprintf("failover: writes switched from endpoint %d to %d", from, i)})
+}
+
+// recover starts a check of the failed endpoint and waits for it until ctx is done.
+// It returns true if writes go to a different endpoint than failed afterwards.
+// Only one check runs at a time, calls failing while it's running don't wait for it and return false.
+func (f *failover) recover(ctx context.Context, cfg *Config, failed *sql.DB) bool {
+	done, switched := f.check(cfg, failed)
+	if switched {
+		return true
+	}
+
+	if done == nil {
+		return false
+	}
+
+	select {
+	case <-done:
+		return f.db() != failed
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// check starts a background check of the failed endpoint, which switches writes to the next writable endpoint
+// if it's still the active one. It returns a channel closed when the check is finished, nil if a check
+// is already running, or true if writes were already switched to another endpoint.
+func (f *failover) check(cfg *Config, failed *sql.DB) (<-chan struct{}, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.endpoints[f.active] != failed {
+		return nil, true
+	}
+
+	if f.recovering != nil {
+		return nil, false
+	}
+
+	done := make(chan struct{})
+	f.recovering = done
+
+	go func() {
+		// the check must not be cut short by the deadline of the failed call
+		f.switchFrom(context.Background(), cfg, failed)
+
+		f.mu.Lock()
+		f.recovering = nil
+		f.mu.Unlock()
+
+		close(done)
+	}()
+
+	return done, false
+}
+
+func (f *failover) switchFrom(ctx context.Context, cfg *Config, failed *sql.DB) {
+	f.switching.Lock()
+	defer f.switching.Unlock()
+
+	if f.db() != failed || isWritable(ctx, failed, cfg.FailoverCheckTimeout) {
+		return
+	}
+
+	active := f.activeIndex()
+	for n := 1; n < len(f.endpoints); n++ {
+		i := (active + n) % len(f.endpoints)
+
+		if isWritable(ctx, f.endpoints[i], cfg.FailoverCheckTimeout) {
+			f.activate(ctx, cfg, i)
+			return
+		}
+	}
+
+	logEvent(ctx, cfg, LevelError, LogEvent{Message: "failover: no writable endpoint found"})
+}
+
+// failback switches writes to the first writable endpoint preceding the active one in the list.
+// Checks are made in the background at most once per Config.FailbackInterval.
+func (f *failover) failback(ctx context.Context, cfg *Config) {
+	if f.activeIndex() == 0 {
+		return
+	}
+
+	now := time.Now().UnixNano()
+	if now-atomic.LoadInt64(&f.lastFailback) < int64(cfg.FailbackInterval) || !atomic.CompareAndSwapInt32(&f.failingBack, 0, 1) {
+		return
+	}
+	atomic.StoreInt64(&f.lastFailback, now)
+
+	go func() {
+		defer atomic.StoreInt32(&f.failingBack, 0)
+
+		f.switching.Lock()
+		defer f.switching.Unlock()
+
+		for i := 0; i < f.activeIndex(); i++ {
+			if isWritable(context.Background(), f.endpoints[i], cfg.FailoverCheckTimeout) {
+				f.activate(ctx, cfg, i)
+				return
+			}
+		}
+	}()
+}
+
+func isWritable(ctx context.Context, db *sql.DB, timeout time.Duration) bool {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var readOnly int
+	if err := db.QueryRowContext(ctx, "SELECT @@read_only").Scan(&readOnly); err != nil {
+		return false
+	}
+
+	return readOnly == 0
+}
+
+// isFailoverError checks if the error means writes can't be made on the endpoint.
+func isFailoverError(err error) bool {
+	return IsConnectionError(err) || IsErrorCode(err, ErrMySQLReadOnly)
+}
+
+// isNotApplied checks if the failed statement certainly wasn't executed, so it can be retried on another endpoint.
+func isNotApplied(err error) bool {
+	if IsErrorCode(err, ErrMySQLReadOnly) {
+		return true
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// primary returns the endpoint writes go to.
+func (c *Client) primary() *sql.DB {
+	if c.cfg().FailbackPolicy == FailbackPreferred && len(c.f.endpoints) > 1 {
+		c.f.failback(context.Background(), c.cfg())
+	}
+
+	return c.f.db()
+}
+
+// failover switches writes to the next writable endpoint if err means db failed.
+// It returns true if the statement can be retried on the new endpoint.
+func (c *Client) failover(ctx context.Context, db *sql.DB, err error) bool {
+	if len(c.f.endpoints) < 2 || !isFailoverError(err) {
+		return false
+	}
+
+	return c.f.recover(ctx, c.cfg(), db) && isNotApplied(err)
+}
+
+// failover starts a check of the endpoint in the background if err means writes can't be made on it.
+// The transaction itself isn't retried.
+func (t *Transaction) failover(err error) {
+	if len(t.f.endpoints) > 1 && isFailoverError(err) {
+		t.f.check(t.config, t.db)
+	}
+}
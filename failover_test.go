@@ -0,0 +1,114 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mysql "github.com/go-sql-driver/mysql"
+)
+
+// readOnlyPrimary returns a read-only endpoint rejecting updates. Checks of @@read_only wait until unblock is closed.
+func readOnlyPrimary(unblock chan struct{}) *fakeDriver {
+	return &fakeDriver{readOnly: 1, handler: func(query string) error {
+		switch query {
+		case "SELECT @@read_only":
+			<-unblock
+		case "BEGIN", "ROLLBACK":
+		default:
+			return &mysql.MySQLError{Number: ErrMySQLReadOnly, Message: "read only"}
+		}
+		return nil
+	}}
+}
+
+func TestExecIsRetriedAfterFailover(t *testing.T) {
+	unblock := make(chan struct{})
+	close(unblock)
+
+	primary, standby := readOnlyPrimary(unblock), &fakeDriver{}
+	c := newFakeFailoverClient(t, []*fakeDriver{primary, standby}, nil)
+
+	if _, err := c.Exec(context.Background(), "UPDATE `foo` SET `bar` = 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if active := c.f.activeIndex(); active != 1 {
+		t.Errorf("expected writes on the standby, got endpoint %d", active)
+	}
+	if executed := standby.executed(); len(executed) != 2 || executed[1] != "UPDATE `foo` SET `bar` = 1" {
+		t.Errorf("expected the update to be retried on the standby, got %v", executed)
+	}
+}
+
+func TestFailoverRunsSingleCheck(t *testing.T) {
+	unblock := make(chan struct{})
+	primary, standby := readOnlyPrimary(unblock), &fakeDriver{}
+	c := newFakeFailoverClient(t, []*fakeDriver{primary, standby}, nil)
+
+	triggered := make(chan error)
+	go func() {
+		_, err := c.Exec(context.Background(), "UPDATE `foo` SET `bar` = 1")
+		triggered <- err
+	}()
+
+	waitFor(t, func() bool {
+		c.f.mu.RLock()
+		defer c.f.mu.RUnlock()
+
+		return c.f.recovering != nil
+	})
+
+	// calls failing while the check is running don't wait for it
+	start := time.Now()
+	if _, err := c.Exec(context.Background(), "UPDATE `foo` SET `bar` = 2"); !IsErrorCode(err, ErrMySQLReadOnly) {
+		t.Errorf("expected ErrMySQLReadOnly, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Millisecond*100 {
+		t.Errorf("expected the call to fail fast, it took %s", elapsed)
+	}
+
+	close(unblock)
+	if err := <-triggered; err != nil {
+		t.Errorf("expected the call triggering the check to be retried, got %v", err)
+	}
+}
+
+func TestFailoverCheckIsNotWaitedForAfterDeadline(t *testing.T) {
+	unblock := make(chan struct{})
+	defer close(unblock)
+
+	primary, standby := readOnlyPrimary(unblock), &fakeDriver{}
+	c := newFakeFailoverClient(t, []*fakeDriver{primary, standby}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	if _, err := c.Exec(ctx, "UPDATE `foo` SET `bar` = 1"); !IsErrorCode(err, ErrMySQLReadOnly) {
+		t.Errorf("expected ErrMySQLReadOnly, got %v", err)
+	}
+}
+
+func TestReadOnlyErrorInTransactionTriggersFailover(t *testing.T) {
+	unblock := make(chan struct{})
+	close(unblock)
+
+	primary, standby := readOnlyPrimary(unblock), &fakeDriver{}
+	c := newFakeFailoverClient(t, []*fakeDriver{primary, standby}, nil)
+
+	tx, err := c.Begin(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tx.Exec(context.Background(), "UPDATE `foo` SET `bar` = 1"); !IsErrorCode(err, ErrMySQLReadOnly) {
+		t.Errorf("expected ErrMySQLReadOnly, got %v", err)
+	}
+	if err := tx.Rollback(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, func() bool {
+		return c.f.activeIndex() == 1
+	})
+}
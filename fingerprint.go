@@ -0,0 +1,172 @@
+package mysql
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	fingerprintStrings     = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'|"(?:[^"\\]|\\.|"")*"`)
+	fingerprintTokens      = regexp.MustCompile(fingerprintStrings.String() + `|(?s:/\*.*?\*/)|(?m:(?:--\s|#).*$)`)
+	fingerprintNumbers     = regexp.MustCompile(`(?i)\b(?:0x[0-9a-f]+|[0-9]+(?:\.[0-9]+)?(?:e[+-]?[0-9]+)?)\b`)
+	fingerprintNegative    = regexp.MustCompile(`([=<>(,\s])-\?`)
+	fingerprintLists       = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	fingerprintValues      = regexp.MustCompile(`(values\s*)\(\?(?:,\s*\?)*\)(?:\s*,\s*\(\?(?:,\s*\?)*\))*`)
+	fingerprintWhitespaces = regexp.MustCompile(`\s+`)
+)
+
+// Fingerprint normalizes a query in pt-query-digest style, so queries differing only in values share a fingerprint:
+// comments are removed, literals are replaced with ?, IN lists and multi-row VALUES are collapsed,
+// whitespaces are collapsed and the query is lowercased.
+//
+//  Fingerprint("SELECT * FROM `foo` WHERE id IN (1, 2, 3) AND name = 'bar'")
+//  // select * from `foo` where id in(?+) and name = ?
+func Fingerprint(query string) string {
+	// strings and comments are matched in one pass, so comment markers in strings and quotes in comments are ignored
+	q := fingerprintTokens.ReplaceAllStringFunc(query, func(token string) string {
+		if token[0] == '\'' || token[0] == '"' {
+			return "?"
+		}
+		return ""
+	})
+	q = strings.ToLower(q)
+	q = fingerprintNumbers.ReplaceAllString(q, "?")
+	q = fingerprintNegative.ReplaceAllString(q, "${1}?")
+	q = fingerprintWhitespaces.ReplaceAllString(q, " ")
+	q = fingerprintValues.ReplaceAllString(q, "${1}(?+)")
+	q = fingerprintLists.ReplaceAllString(q, "(?+)")
+	q = strings.Replace(q, " in (?+)", " in(?+)", -1)
+	q = strings.TrimSpace(q)
+	q = strings.TrimRight(q, ";")
+
+	return strings.TrimSpace(q)
+}
+
+// QueryDigest aggregates stats of queries sharing a fingerprint.
+//
+type QueryDigest struct {
+	Fingerprint        string
+	Count              int64
+	Errors             int64
+	TotalExecutionTime time.Duration
+	MaxExecutionTime   time.Duration
+	Rows               int64 // rows returned or affected
+}
+
+// DigestOrder orders queries in a TopQueries report.
+//
+type DigestOrder int
+
+const (
+	ByTotalExecutionTime DigestOrder = iota
+	ByMaxExecutionTime
+	ByCount
+	ByErrors
+	ByRows
+)
+
+// digests keeps aggregates of at most limit fingerprints, new fingerprints are ignored when the limit is reached.
+type digests struct {
+	mu    sync.Mutex
+	limit int
+	m     map[string]*QueryDigest
+	cache map[string]string // fingerprints of raw queries
+}
+
+func newDigests() *digests {
+	return &digests{
+		m:     make(map[string]*QueryDigest),
+		cache: make(map[string]string),
+	}
+}
+
+func (d *digests) setLimit(limit int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.limit = limit
+}
+
+func (d *digests) observe(query string, executionTime time.Duration, rows int64, failed bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.limit <= 0 {
+		return
+	}
+
+	fingerprint, found := d.cache[query]
+	if !found {
+		fingerprint = Fingerprint(query)
+		if len(d.cache) >= d.limit*digestCacheRatio {
+			d.cache = make(map[string]string)
+		}
+		d.cache[query] = fingerprint
+	}
+
+	digest, found := d.m[fingerprint]
+	if !found {
+		if len(d.m) >= d.limit {
+			return
+		}
+
+		digest = &QueryDigest{Fingerprint: fingerprint}
+		d.m[fingerprint] = digest
+	}
+
+	digest.Count++
+	digest.TotalExecutionTime += executionTime
+	digest.Rows += rows
+
+	if executionTime > digest.MaxExecutionTime {
+		digest.MaxExecutionTime = executionTime
+	}
+
+	if failed {
+		digest.Errors++
+	}
+}
+
+// digestCacheRatio limits the number of cached raw queries to a multiple of the digest limit
+const digestCacheRatio = 10
+
+func (d *digests) top(n int, order DigestOrder) []QueryDigest {
+	d.mu.Lock()
+	top := make([]QueryDigest, 0, len(d.m))
+	for _, digest := range d.m {
+		top = append(top, *digest)
+	}
+	d.mu.Unlock()
+
+	key := func(q QueryDigest) int64 {
+		switch order {
+		case ByMaxExecutionTime:
+			return int64(q.MaxExecutionTime)
+		case ByCount:
+			return q.Count
+		case ByErrors:
+			return q.Errors
+		case ByRows:
+			return q.Rows
+		}
+
+		return int64(q.TotalExecutionTime)
+	}
+
+	sort.Slice(top, func(i, j int) bool {
+		if ki, kj := key(top[i]), key(top[j]); ki != kj {
+			return ki > kj
+		}
+
+		return top[i].Fingerprint < top[j].Fingerprint
+	})
+
+	if n > 0 && n < len(top) {
+		top = top[:n]
+	}
+
+	return top
+}
@@ -0,0 +1,25 @@
+package mysql
+
+import "testing"
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		query       string
+		fingerprint string
+	}{
+		{"SELECT * FROM `foo` WHERE id IN (1, 2, 3) AND name = 'bar'", "select * from `foo` where id in(?+) and name = ?"},
+		{"SELECT * FROM foo WHERE color = '#fff' AND id = 1", "select * from foo where color = ? and id = ?"},
+		{"SELECT * FROM foo WHERE note = '-- x' AND id = 1", "select * from foo where note = ? and id = ?"},
+		{"SELECT * FROM foo WHERE note = \"/* x */\"", "select * from foo where note = ?"},
+		{"SELECT /* it's */ * FROM foo WHERE id = 1 -- that's it", "select * from foo where id = ?"},
+		{"SELECT * FROM foo # it's\nWHERE id = -1;", "select * from foo where id = ?"},
+		{"INSERT INTO foo (a, b) VALUES (1, 'x'), (2, 'y')", "insert into foo (a, b) values (?+)"},
+		{"SELECT * FROM foo WHERE hash = 0xDEADBEEF", "select * from foo where hash = ?"},
+	}
+
+	for _, test := range tests {
+		if fingerprint := Fingerprint(test.query); fingerprint != test.fingerprint {
+			t.Errorf("%s: expected %q, got %q", test.query, test.fingerprint, fingerprint)
+		}
+	}
+}
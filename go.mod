@@ -2,4 +2,4 @@ module github.com/livechat/go-mysql
 
 go 1.14
 
-require github.com/go-sql-driver/mysql v1.6.0
+require github.com/go-sql-driver/mysql v1.7.1
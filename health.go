@@ -0,0 +1,166 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Health describes the state of the client endpoints.
+//
+type Health struct {
+	Healthy      bool             `json:"healthy"` // all endpoints are reachable, the primary is writable and the circuit isn't open
+	Primary      EndpointHealth   `json:"primary"`
+	Replicas     []EndpointHealth `json:"replicas,omitempty"`
+	CircuitState CircuitState     `json:"circuit_state"`
+}
+
+// EndpointHealth describes the state of a single endpoint.
+//
+type EndpointHealth struct {
+	Healthy          bool           `json:"healthy"`
+	Error            string         `json:"error,omitempty"`
+	Latency          time.Duration  `json:"latency"` // round-trip time of the health query in nanoseconds
+	ReadOnly         bool           `json:"read_only"`
+	ReplicationLag   *time.Duration `json:"replication_lag,omitempty"`   // reported by replicas only, nil if replication isn't running or its status can't be read
+	ReplicationError string         `json:"replication_error,omitempty"` // why the replication status can't be read, e.g. missing REPLICATION CLIENT privilege
+	Saturation       float64        `json:"saturation"`                  // connections in use to max open connections, 0 if there is no limit
+	DBStats          sql.DBStats    `json:"db_stats"`
+}
+
+// Ping verifies the connection to the endpoint writes go to.
+//
+func (c *Client) Ping(ctx context.Context) error {
+	return c.primary().PingContext(ctx)
+}
+
+// Health checks the primary and the replica. Health queries bypass the queue, so they report the database state
+// even when the client is overloaded.
+//
+func (c *Client) Health(ctx context.Context) *Health {
+	state, _ := c.b.stats()
+
+	h := &Health{
+		Primary:      endpointHealth(ctx, c.primary(), false),
+		CircuitState: state,
+	}
+	h.Healthy = h.Primary.Healthy && !h.Primary.ReadOnly && state != CircuitOpen
+
+	if c.replica != nil {
+		replica := endpointHealth(ctx, c.replica.primary(), true)
+		h.Replicas = append(h.Replicas, replica)
+		h.Healthy = h.Healthy && replica.Healthy
+	}
+
+	return h
+}
+
+// HealthHandler serves Health as JSON, the status is 503 if the client isn't healthy.
+// It can be used for readiness probes.
+//
+//  http.Handle("/health/mysql", client.HealthHandler(time.Second))
+func (c *Client) HealthHandler(timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		h := c.Health(ctx)
+
+		w.Header().Set("Content-Type", "application/json")
+		if !h.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		json.NewEncoder(w).Encode(h)
+	})
+}
+
+func endpointHealth(ctx context.Context, db *sql.DB, replica bool) EndpointHealth {
+	h := EndpointHealth{DBStats: db.Stats()}
+	if h.DBStats.MaxOpenConnections > 0 {
+		h.Saturation = float64(h.DBStats.InUse) / float64(h.DBStats.MaxOpenConnections)
+	}
+
+	start := time.Now()
+	var readOnly int
+	err := db.QueryRowContext(ctx, "SELECT @@read_only").Scan(&readOnly)
+	h.Latency = time.Now().Sub(start)
+
+	if err != nil {
+		h.Error = err.Error()
+		return h
+	}
+
+	h.Healthy = true
+	h.ReadOnly = readOnly != 0
+
+	if replica {
+		// the endpoint answered, so it stays healthy when only its replication status can't be read
+		lag, err := replicationLag(ctx, db)
+		if err != nil {
+			h.ReplicationError = err.Error()
+		}
+		h.ReplicationLag = lag
+	}
+
+	return h
+}
+
+// replicationLag returns Seconds_Behind_Source, nil if replication isn't running.
+func replicationLag(ctx context.Context, db *sql.DB) (*time.Duration, error) {
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		// MySQL older than 8.0.22
+		rows, err = db.QueryContext(ctx, "SHOW SLAVE STATUS")
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	values := make([]sql.RawBytes, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	if err := rows.Scan(pointers...); err != nil {
+		return nil, err
+	}
+
+	for i, column := range columns {
+		if column != "Seconds_Behind_Source" && column != "Seconds_Behind_Master" {
+			continue
+		}
+
+		if values[i] == nil {
+			return nil, nil
+		}
+
+		seconds, err := strconv.ParseInt(string(values[i]), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		lag := time.Duration(seconds) * time.Second
+		return &lag, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,46 @@
+package mysql
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	mysql "github.com/go-sql-driver/mysql"
+)
+
+func TestHealthWithoutReplicationPrivilege(t *testing.T) {
+	replica := &fakeDriver{readOnly: 1, handler: func(query string) error {
+		if strings.HasPrefix(query, "SHOW") {
+			return &mysql.MySQLError{Number: 1227, Message: "Access denied; you need the REPLICATION CLIENT privilege"}
+		}
+		return nil
+	}}
+
+	c := newFakeClient(t, &fakeDriver{}, nil)
+	c.SetReplica(newFakeClient(t, replica, nil))
+
+	h := c.Health(context.Background())
+	if !h.Healthy || len(h.Replicas) != 1 {
+		t.Fatalf("expected a healthy client with a replica, got %+v", h)
+	}
+
+	r := h.Replicas[0]
+	if !r.Healthy || !r.ReadOnly || r.ReplicationLag != nil || r.Error != "" {
+		t.Errorf("expected a healthy replica without lag, got %+v", r)
+	}
+	if !strings.Contains(r.ReplicationError, "1227") {
+		t.Errorf("expected the replication error, got %q", r.ReplicationError)
+	}
+}
+
+func TestHealthOfUnreachablePrimary(t *testing.T) {
+	primary := &fakeDriver{handler: func(query string) error {
+		return mysql.ErrInvalidConn
+	}}
+	c := newFakeClient(t, primary, nil)
+
+	h := c.Health(context.Background())
+	if h.Healthy || h.Primary.Healthy || h.Primary.Error == "" {
+		t.Errorf("expected an unhealthy primary with an error, got %+v", h)
+	}
+}
@@ -0,0 +1,117 @@
+package mysql
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+var (
+	// latency buckets in seconds, from 1ms to 10s
+	durationBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+	// buckets of a number of queries
+	countBuckets = []float64{0, 1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000, 10000}
+)
+
+// Histogram is a snapshot of observed values distribution.
+//
+type Histogram struct {
+	Buckets []Bucket // buckets sorted by upper bound, the last bucket counts all values
+	Count   int64    // number of observed values
+	Sum     float64  // sum of observed values
+	P50     float64  // percentiles estimated from buckets
+	P95     float64
+	P99     float64
+}
+
+// Bucket counts values less than or equal to the upper bound, counts are cumulative.
+//
+type Bucket struct {
+	UpperBound float64
+	Count      int64
+}
+
+type histogram struct {
+	mu     sync.Mutex
+	bounds []float64
+	counts []int64 // the last count is for values greater than all bounds
+	count  int64
+	sum    float64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{
+		bounds: bounds,
+		counts: make([]int64, len(bounds)+1),
+	}
+}
+
+func (h *histogram) observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v)
+
+	h.mu.Lock()
+	h.counts[i]++
+	h.count++
+	h.sum += v
+	h.mu.Unlock()
+}
+
+func (h *histogram) snapshot() Histogram {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := Histogram{
+		Buckets: make([]Bucket, len(h.counts)),
+		Count:   h.count,
+		Sum:     h.sum,
+	}
+
+	var cumulative int64
+	for i, c := range h.counts {
+		cumulative += c
+		s.Buckets[i].Count = cumulative
+
+		if i < len(h.bounds) {
+			s.Buckets[i].UpperBound = h.bounds[i]
+		} else {
+			s.Buckets[i].UpperBound = math.Inf(1)
+		}
+	}
+
+	s.P50 = s.Quantile(.5)
+	s.P95 = s.Quantile(.95)
+	s.P99 = s.Quantile(.99)
+
+	return s
+}
+
+// Quantile estimates the q-quantile (0 <= q <= 1) with linear interpolation within a bucket.
+// Values above the highest finite bound are reported as that bound, 0 is returned if there are no values.
+//
+func (h Histogram) Quantile(q float64) float64 {
+	if h.Count == 0 || len(h.Buckets) == 0 {
+		return 0
+	}
+
+	rank := q * float64(h.Count)
+
+	var (
+		lowerBound float64
+		lowerCount int64
+	)
+
+	for _, b := range h.Buckets {
+		if float64(b.Count) >= rank && b.Count > lowerCount {
+			if math.IsInf(b.UpperBound, 1) {
+				return lowerBound
+			}
+
+			return lowerBound + (b.UpperBound-lowerBound)*(rank-float64(lowerCount))/float64(b.Count-lowerCount)
+		}
+
+		lowerBound, lowerCount = b.UpperBound, b.Count
+	}
+
+	return lowerBound
+}
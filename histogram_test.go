@@ -0,0 +1,54 @@
+package mysql
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHistogramQuantile(t *testing.T) {
+	inf := math.Inf(1)
+
+	// 2 values in (0, 1], none in (1, 2], 4 in (2, 4]
+	withEmptyBucket := Histogram{Buckets: []Bucket{{1, 2}, {2, 2}, {4, 6}, {inf, 6}}, Count: 6}
+	// 1 value in (0, 1], 3 above the highest finite bound
+	aboveTopBound := Histogram{Buckets: []Bucket{{1, 1}, {2, 1}, {inf, 4}}, Count: 4}
+
+	tests := []struct {
+		name     string
+		h        Histogram
+		q        float64
+		expected float64
+	}{
+		{"no values", Histogram{Buckets: []Bucket{{1, 0}, {inf, 0}}}, .5, 0},
+		{"no buckets", Histogram{Count: 1}, .5, 0},
+		{"minimum", withEmptyBucket, 0, 0},
+		{"interpolated within the first bucket", withEmptyBucket, 1. / 6, .5},
+		{"upper bound of a bucket", withEmptyBucket, 1. / 3, 1},
+		{"empty bucket is skipped", withEmptyBucket, .5, 2.5},
+		{"maximum", withEmptyBucket, 1, 4},
+		{"below the highest finite bound", aboveTopBound, .25, 1},
+		{"above the highest finite bound", aboveTopBound, .5, 2},
+		{"maximum above the highest finite bound", aboveTopBound, 1, 2},
+	}
+
+	for _, test := range tests {
+		if q := test.h.Quantile(test.q); math.Abs(q-test.expected) > 1e-9 {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, q)
+		}
+	}
+}
+
+func TestHistogramSnapshotPercentiles(t *testing.T) {
+	h := newHistogram([]float64{1, 2, 4})
+	for _, v := range []float64{.5, 3, 3, 10} {
+		h.observe(v)
+	}
+
+	s := h.snapshot()
+	if s.Count != 4 || s.Sum != 16.5 {
+		t.Errorf("expected 4 values with sum 16.5, got %d and %v", s.Count, s.Sum)
+	}
+	if s.P50 != 3 || s.P99 != 4 {
+		t.Errorf("expected p50 3 and p99 4, got %v and %v", s.P50, s.P99)
+	}
+}
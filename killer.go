@@ -0,0 +1,179 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	killQueryTimeout = time.Second * 5
+	killPoolSize     = 2 // connections used to kill queries of an endpoint
+
+	connectionIDQuery = "SELECT CONNECTION_ID()"
+)
+
+// queryer is implemented by sql.DB, sql.Conn and sql.Tx.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// killer issues KILL QUERY for statements which are still running on the server when their context is cancelled.
+// KILL QUERY is sent over a separate pool of the endpoint, so it isn't blocked when all of its connections are busy.
+type killer struct {
+	mu      sync.Mutex
+	queries map[killKey]*watchedQuery // in-flight queries by endpoint and connection id
+	pools   map[*sql.DB]*sql.DB       // pools used to kill queries by endpoint
+	kills   *int64
+}
+
+// killKey identifies a connection, ids are unique only within an endpoint.
+type killKey struct {
+	db *sql.DB
+	id uint64
+}
+
+type watchedQuery struct {
+	query   string
+	running int32 // set while the statement runs on the server
+}
+
+func newKiller(endpoints []*sql.DB, connectors []driver.Connector) *killer {
+	pools := make(map[*sql.DB]*sql.DB, len(endpoints))
+	for i, db := range endpoints {
+		pool := sql.OpenDB(connectors[i])
+		pool.SetMaxOpenConns(killPoolSize)
+		pool.SetMaxIdleConns(1)
+		pools[db] = pool
+	}
+
+	return &killer{
+		queries: make(map[killKey]*watchedQuery),
+		pools:   pools,
+		kills:   new(int64),
+	}
+}
+
+func (k *killer) close() {
+	for _, pool := range k.pools {
+		pool.Close()
+	}
+}
+
+// acquire returns a queryer of db for a single statement and a function releasing it.
+// When Config.KillQueryOnCancel is enabled, the statement runs on a dedicated connection, the connection id is tracked
+// until the release and the query is killed if the context is cancelled while it's running.
+// It costs one additional roundtrip per statement.
+func (c *Client) acquire(ctx context.Context, db *sql.DB, query string) (queryer, func(), error) {
+	if !c.cfg().KillQueryOnCancel {
+		return db, func() {}, nil
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var id uint64
+	if err := conn.QueryRowContext(ctx, connectionIDQuery).Scan(&id); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	w := &watchedQuery{query: query}
+	stop := c.k.watch(ctx, c.cfg(), db, id, w)
+
+	return &watchedConn{conn, w}, func() {
+		// rows of a query are read by now, unless they were abandoned because of the context
+		if ctx.Err() == nil {
+			atomic.StoreInt32(&w.running, 0)
+		}
+		stop()
+		conn.Close()
+	}, nil
+}
+
+// watchedConn marks the watched query as running while its statement is executed.
+// A query stays running until the connection is released, while its rows are read.
+// A statement abandoned because of the context stays running, it's up to the watcher to kill it.
+type watchedConn struct {
+	*sql.Conn
+	w *watchedQuery
+}
+
+func (c *watchedConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	atomic.StoreInt32(&c.w.running, 1)
+
+	result, err := c.Conn.ExecContext(ctx, query, args...)
+	// a statement abandoned by the driver because of the context may still be running on the server
+	if err == nil || ctx.Err() == nil {
+		atomic.StoreInt32(&c.w.running, 0)
+	}
+
+	return result, err
+}
+
+func (c *watchedConn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	atomic.StoreInt32(&c.w.running, 1)
+
+	return c.Conn.QueryContext(ctx, query, args...)
+}
+
+// watch kills the query running on the connection when ctx is done before stop is called.
+// stop waits for the kill to finish, so the connection isn't reused in the meantime.
+func (k *killer) watch(ctx context.Context, cfg *Config, db *sql.DB, id uint64, w *watchedQuery) (stop func()) {
+	k.mu.Lock()
+	k.queries[killKey{db, id}] = w
+	k.mu.Unlock()
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+
+		select {
+		case <-ctx.Done():
+			k.kill(ctx, cfg, db, id)
+		case <-done:
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-exited
+
+		k.mu.Lock()
+		delete(k.queries, killKey{db, id})
+		k.mu.Unlock()
+	}
+}
+
+// kill sends KILL QUERY when the watched statement is still running, a statement which has already finished
+// isn't killed nor counted.
+func (k *killer) kill(ctx context.Context, cfg *Config, db *sql.DB, id uint64) {
+	k.mu.Lock()
+	w, found := k.queries[killKey{db, id}]
+	pool := k.pools[db]
+	k.mu.Unlock()
+
+	if !found || pool == nil || atomic.LoadInt32(&w.running) == 0 {
+		return
+	}
+
+	killCtx, cancel := context.WithTimeout(context.Background(), killQueryTimeout)
+	defer cancel()
+
+	atomic.AddInt64(k.kills, 1)
+	kill := "KILL QUERY " + strconv.FormatUint(id, 10)
+	logEvent(ctx, cfg, LevelWarning, LogEvent{Message: kill, Query: w.query})
+
+	if _, err := pool.ExecContext(killCtx, kill); err != nil {
+		logEvent(ctx, cfg, LevelError, LogEvent{Message: "kill query", Query: kill, Err: err})
+	}
+}
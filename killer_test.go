@@ -0,0 +1,114 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKillQueryUsesSeparatePool(t *testing.T) {
+	d := &fakeDriver{delay: time.Second}
+	c := newFakeClient(t, d, func(cfg *Config) {
+		cfg.KillQueryOnCancel = true
+		cfg.MaxOpenConns = 1
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := c.Exec(ctx, "SELECT SLEEP(10)"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	// the only connection of the endpoint is busy until the kill is finished
+	if elapsed := time.Since(start); elapsed > killQueryTimeout/2 {
+		t.Errorf("expected the kill not to wait for a connection of the endpoint, it took %s", elapsed)
+	}
+	if killed := c.Stats().TotalKilledQueries; killed != 1 {
+		t.Errorf("expected 1 killed query, got %d", killed)
+	}
+	if !containsPrefix(d.executed(), "KILL QUERY ") {
+		t.Errorf("expected KILL QUERY, got %v", d.executed())
+	}
+}
+
+func TestKillSkipsFinishedStatements(t *testing.T) {
+	d := &fakeDriver{}
+	db := sql.OpenDB(d)
+	defer db.Close()
+
+	k := newKiller([]*sql.DB{db}, []driver.Connector{d})
+	defer k.close()
+
+	tests := []struct {
+		running bool
+		kills   int64
+	}{
+		{false, 0},
+		{true, 1},
+	}
+
+	for i, test := range tests {
+		w := &watchedQuery{query: "SELECT 1"}
+		if test.running {
+			w.running = 1
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		stop := k.watch(ctx, NewDefaultConfig(), db, uint64(i+1), w)
+		cancel()
+		time.Sleep(time.Millisecond * 10)
+		stop()
+
+		if kills := *k.kills; kills != test.kills {
+			t.Errorf("running %v: expected %d kills, got %d", test.running, test.kills, kills)
+		}
+	}
+
+	if executed := d.executed(); len(executed) != 1 || executed[0] != "KILL QUERY 2" {
+		t.Errorf("expected only KILL QUERY 2, got %v", executed)
+	}
+}
+
+func containsPrefix(queries []string, prefix string) bool {
+	for _, q := range queries {
+		if strings.HasPrefix(q, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestKillDistinguishesEndpoints(t *testing.T) {
+	primary, standby := &fakeDriver{}, &fakeDriver{}
+	primaryDB, standbyDB := sql.OpenDB(primary), sql.OpenDB(standby)
+	defer primaryDB.Close()
+	defer standbyDB.Close()
+
+	k := newKiller([]*sql.DB{primaryDB, standbyDB}, []driver.Connector{primary, standby})
+	defer k.close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// both endpoints run a query on a connection with the same id
+	stopPrimary := k.watch(ctx, NewDefaultConfig(), primaryDB, 1, &watchedQuery{"SELECT 1", 1})
+	stopStandby := k.watch(context.Background(), NewDefaultConfig(), standbyDB, 1, &watchedQuery{"SELECT 2", 1})
+	stopStandby()
+
+	cancel()
+	time.Sleep(time.Millisecond * 10)
+	stopPrimary()
+
+	if executed := primary.executed(); len(executed) != 1 || executed[0] != "KILL QUERY 1" {
+		t.Errorf("expected KILL QUERY 1 on the primary, got %v", executed)
+	}
+	if executed := standby.executed(); len(executed) != 0 {
+		t.Errorf("expected nothing on the standby, got %v", executed)
+	}
+}
@@ -0,0 +1,83 @@
+package mysql
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const adaptiveLimitBackoff = 0.9
+
+// limiter adjusts the limit of in-flight queries with AIMD: the limit grows by one per limit of fast queries
+// and is multiplied by adaptiveLimitBackoff when a query is slower than the target latency.
+type limiter struct {
+	mu           sync.Mutex
+	enabled      bool
+	target       time.Duration
+	min          float64
+	max          float64
+	limit        float64
+	lastDecrease time.Time
+}
+
+func newLimiter() *limiter {
+	return &limiter{}
+}
+
+func (l *limiter) configure(cfg *Config) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.enabled = cfg.AdaptiveLimit
+	l.target = cfg.AdaptiveLimitLatency
+	if l.target <= 0 {
+		// every query would be slower than the target, the limit would stay at the minimum
+		l.target = NewDefaultConfig().AdaptiveLimitLatency
+	}
+	l.min = math.Max(float64(cfg.AdaptiveLimitMin), 1)
+	l.max = math.Max(float64(cfg.AdaptiveLimitMax), l.min)
+
+	if l.limit == 0 || l.limit > l.max {
+		l.limit = l.max
+	}
+
+	if l.limit < l.min {
+		l.limit = l.min
+	}
+}
+
+func (l *limiter) observe(executionTime time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.enabled {
+		return
+	}
+
+	if executionTime <= l.target {
+		l.limit = math.Min(l.limit+1/l.limit, l.max)
+		return
+	}
+
+	// queries running at once when the database slows down report the same slowdown,
+	// the limit is decreased once per target latency
+	now := time.Now()
+	if now.Sub(l.lastDecrease) < l.target {
+		return
+	}
+
+	l.lastDecrease = now
+	l.limit = math.Max(l.limit*adaptiveLimitBackoff, l.min)
+}
+
+// current returns the current limit, 0 if adaptive limit is disabled.
+func (l *limiter) current() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.enabled {
+		return 0
+	}
+
+	return int64(l.limit)
+}
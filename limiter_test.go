@@ -0,0 +1,122 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestLimiter(min, max int64) *limiter {
+	cfg := NewDefaultConfig()
+	cfg.AdaptiveLimit = true
+	cfg.AdaptiveLimitLatency = time.Millisecond * 10
+	cfg.AdaptiveLimitMin = min
+	cfg.AdaptiveLimitMax = max
+
+	l := newLimiter()
+	l.configure(cfg)
+	return l
+}
+
+func TestLimiter(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max int64
+		observe  func(l *limiter)
+		limit    int64
+	}{
+		{"starts from max", 2, 10, func(l *limiter) {}, 10},
+		{"decreases on slow queries", 2, 10, func(l *limiter) {
+			l.observe(time.Millisecond * 20)
+		}, 9},
+		{"decreases once per target latency", 2, 10, func(l *limiter) {
+			l.observe(time.Millisecond * 20)
+			l.observe(time.Millisecond * 20)
+			l.observe(time.Millisecond * 20)
+		}, 9},
+		{"doesn't go below min", 9, 10, func(l *limiter) {
+			for i := 0; i < 3; i++ {
+				l.observe(time.Millisecond * 20)
+				time.Sleep(time.Millisecond * 10)
+			}
+		}, 9},
+		{"grows by one per limit of fast queries", 2, 20, func(l *limiter) {
+			l.observe(time.Millisecond * 20)
+			for i := 0; i < 20; i++ {
+				l.observe(time.Millisecond)
+			}
+		}, 19},
+		{"doesn't grow above max", 2, 10, func(l *limiter) {
+			for i := 0; i < 100; i++ {
+				l.observe(time.Millisecond)
+			}
+		}, 10},
+	}
+
+	for _, test := range tests {
+		l := newTestLimiter(test.min, test.max)
+		test.observe(l)
+
+		if limit := l.current(); limit != test.limit {
+			t.Errorf("%s: expected limit %d, got %d", test.name, test.limit, limit)
+		}
+	}
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	l := newLimiter()
+	l.configure(NewDefaultConfig())
+	l.observe(time.Hour)
+
+	if limit := l.current(); limit != 0 {
+		t.Errorf("expected no limit, got %d", limit)
+	}
+}
+
+func TestAdaptiveLimitRejectsCalls(t *testing.T) {
+	d := &fakeDriver{delay: time.Millisecond * 100}
+	c := newFakeClient(t, d, func(cfg *Config) {
+		cfg.AdaptiveLimit = true
+		cfg.AdaptiveLimitMin = 1
+		cfg.AdaptiveLimitMax = 1
+	})
+
+	done := make(chan error)
+	go func() {
+		_, err := c.Exec(context.Background(), "UPDATE `foo` SET `bar` = 1")
+		done <- err
+	}()
+
+	waitFor(t, func() bool {
+		return c.Stats().InProgressQueries == 1
+	})
+
+	if _, err := c.Exec(context.Background(), "UPDATE `foo` SET `bar` = 2"); !errors.Is(err, ErrQueueOverloaded) {
+		t.Errorf("expected ErrQueueOverloaded, got %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLimiterWithoutTargetLatency(t *testing.T) {
+	for _, target := range []time.Duration{0, -time.Second} {
+		cfg := NewDefaultConfig()
+		cfg.AdaptiveLimit = true
+		cfg.AdaptiveLimitLatency = target
+		cfg.AdaptiveLimitMin = 5
+		cfg.AdaptiveLimitMax = 100
+
+		l := newLimiter()
+		l.configure(cfg)
+
+		for i := 0; i < 50; i++ {
+			l.observe(time.Microsecond)
+		}
+
+		if limit := l.current(); limit != 100 {
+			t.Errorf("target %s: expected fast queries to keep the limit at 100, got %d", target, limit)
+		}
+	}
+}
@@ -2,16 +2,25 @@ package mysql
 
 import (
 	"context"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
 
 	m "github.com/go-sql-driver/mysql"
 )
 
-var (
-	logger Logger
-)
+const verboseKey contextKey = "verbose"
+
+// globalLogger holds a loggerHolder with the logger set with SetLogger
+var globalLogger atomic.Value
+
+type loggerHolder struct {
+	l Logger
+}
 
 func init() {
-	logger = &defaultLogger{}
+	globalLogger.Store(loggerHolder{&defaultLogger{}})
 }
 
 // Logger interface for enabling logs.
@@ -25,6 +34,194 @@ type Logger interface {
 	Debug(...interface{})   // used queries
 }
 
+// WithVerboseLogging returns a context with all calls logged regardless of Config.LogLevel
+// and Config.LogDebugSampleRate, useful to debug a single request.
+//
+//  res, err := client.Query(mysql.WithVerboseLogging(ctx), "SELECT * FROM `foo`;")
+func WithVerboseLogging(ctx context.Context) context.Context {
+	return context.WithValue(ctx, verboseKey, true)
+}
+
+func isVerbose(ctx context.Context) bool {
+	verbose, _ := ctx.Value(verboseKey).(bool)
+	return verbose
+}
+
+// StructuredLogger is a Logger accepting structured events, see NewSlogLogger.
+// Loggers implementing only Logger get events as positional args.
+//
+type StructuredLogger interface {
+	Logger
+
+	Log(level LogLevel, event LogEvent)
+}
+
+// LogLevel is a level of a LogEvent.
+//
+type LogLevel int
+
+const (
+	LevelDebug LogLevel = iota
+	LevelWarning
+	LevelError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelWarning:
+		return "warning"
+	case LevelError:
+		return "error"
+	}
+
+	return "unknown"
+}
+
+// UnmarshalText parses level names returned by String, like in config files loaded with ConfigFile.
+//
+func (l *LogLevel) UnmarshalText(text []byte) error {
+	for _, level := range []LogLevel{LevelDebug, LevelWarning, LevelError} {
+		if string(text) == level.String() {
+			*l = level
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown log level %q", text)
+}
+
+// LogEvent is a structured log record of a call made by Client or Transaction.
+//
+type LogEvent struct {
+	Message         string        // what happened, like "deadlock" or "slow query", operation for statements
+	Query           string        // query or BEGIN, COMMIT, ROLLBACK
+	Operation       Operation     // type of the call
+	Args            []string      // redacted args, set for slow queries
+	Duration        time.Duration // execution time
+	QueueTime       time.Duration // time spent in queue waiting for connection
+	TransactionTime time.Duration // total transaction time, set for commit and rollback
+	Rows            int64         // rows returned by queries or affected by execs
+	Attempts        int           // number of executions
+	Role            Role          // role of the client which executed the statement
+	TransactionID   uint64        // id of the transaction, 0 outside of transactions
+	Err             error         // error returned to the caller, a QueryError message is redacted like Query
+	Caller          string        // location of the caller outside of the package, set for slow queries
+}
+
+func newLogEvent(sample *QueryStats) LogEvent {
+	return LogEvent{
+		Message:         string(sample.Operation),
+		Query:           sample.Query,
+		Operation:       sample.Operation,
+		Duration:        sample.ExecutionTime,
+		QueueTime:       sample.QueueTime,
+		TransactionTime: sample.TransactionTime,
+		Rows:            sample.RowsReturned + sample.RowsAffected,
+		Attempts:        sample.Attempts,
+		Role:            sample.Role,
+		TransactionID:   sample.TransactionID,
+		Err:             sample.Err,
+	}
+}
+
+// args returns the event as positional args of Logger methods.
+func (e LogEvent) args(level LogLevel) []interface{} {
+	switch level {
+	case LevelError:
+		if e.Err == nil {
+			return []interface{}{e.Message}
+		}
+		if e.Query == "" {
+			return []interface{}{e.Err, e.Message}
+		}
+		return []interface{}{e.Err, e.Query}
+	case LevelDebug:
+		args := []interface{}{e.Query, e.Duration, e.QueueTime}
+		if e.TransactionTime > 0 {
+			args = append(args, e.TransactionTime)
+		}
+		return args
+	}
+
+	args := []interface{}{e.Message}
+	if e.Query != "" {
+		args = append(args, e.Query)
+	}
+	if e.Args != nil {
+		args = append(args, "args", e.Args)
+	}
+	if e.QueueTime > 0 {
+		args = append(args, "queue time", e.QueueTime)
+	}
+	if e.Duration > 0 {
+		args = append(args, "execution time", e.Duration)
+	}
+	if e.Rows > 0 {
+		args = append(args, "rows", e.Rows)
+	}
+	if e.Attempts > 0 {
+		args = append(args, "attempts", e.Attempts)
+	}
+	if e.Caller != "" {
+		args = append(args, "caller", e.Caller)
+	}
+
+	return args
+}
+
+// logger returns Config.Logger or the logger set with SetLogger if it's nil.
+func (c *Config) logger() Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+
+	return globalLogger.Load().(loggerHolder).l
+}
+
+// logEnabled tells if events of the level are logged according to Config.LogLevel and WithVerboseLogging.
+func (c *Config) logEnabled(ctx context.Context, level LogLevel) bool {
+	return level >= c.LogLevel || isVerbose(ctx)
+}
+
+// logEvent logs the event with the logger found in ctx if the level is enabled.
+func logEvent(ctx context.Context, cfg *Config, level LogLevel, event LogEvent) {
+	if !cfg.logEnabled(ctx, level) {
+		return
+	}
+
+	l := cfg.logger().FromCtx(ctx).Tag("mysql")
+	if sl, ok := l.(StructuredLogger); ok {
+		sl.Log(level, event)
+		return
+	}
+
+	switch level {
+	case LevelError:
+		l.Error(event.args(level)...)
+	case LevelWarning:
+		l.Warning(event.args(level)...)
+	default:
+		l.Debug(event.args(level)...)
+	}
+}
+
+// logSample logs a finished call. Failed calls are logged as errors and calls slower than Config.LogLatencyThreshold
+// as warnings, other calls are logged at debug level with Config.LogDebugSampleRate, all of them if the rate is zero.
+func logSample(ctx context.Context, cfg *Config, sample *QueryStats) {
+	switch {
+	case sample.Err != nil:
+		logEvent(ctx, cfg, LevelError, newLogEvent(sample))
+	case sample.slowLogged:
+		// already logged at warning level as a slow query
+	case cfg.LogLatencyThreshold > 0 && sample.QueueTime+sample.ExecutionTime >= cfg.LogLatencyThreshold:
+		logEvent(ctx, cfg, LevelWarning, newLogEvent(sample))
+	case cfg.LogDebugSampleRate >= 1 || cfg.LogDebugSampleRate <= 0 || isVerbose(ctx) || rand.Float64() < cfg.LogDebugSampleRate:
+		logEvent(ctx, cfg, LevelDebug, newLogEvent(sample))
+	}
+}
+
 type defaultLogger struct{}
 
 func (d *defaultLogger) FromCtx(ctx context.Context) Logger {
@@ -48,7 +245,8 @@ func (lw *loggerWrapper) Print(v ...interface{}) {
 
 // The default logger does not log anything, this function overrides default logger.
 // The logger must compatible with a Logger interface.
+// It's used by clients without Config.Logger and by the driver, which has a single logger for the process.
 func SetLogger(l Logger) {
-	logger = l
+	globalLogger.Store(loggerHolder{l})
 	m.SetLogger(&loggerWrapper{l})
 }
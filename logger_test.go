@@ -0,0 +1,88 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingLogger records levels of logged events.
+type recordingLogger struct {
+	mu     sync.Mutex
+	levels []LogLevel
+}
+
+func (l *recordingLogger) FromCtx(ctx context.Context) Logger { return l }
+func (l *recordingLogger) Tag(tag string) Logger              { return l }
+func (l *recordingLogger) Error(p ...interface{})             {}
+func (l *recordingLogger) Warning(p ...interface{})           {}
+func (l *recordingLogger) Debug(p ...interface{})             {}
+
+func (l *recordingLogger) Log(level LogLevel, event LogEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.levels = append(l.levels, level)
+}
+
+func TestLogSample(t *testing.T) {
+	tests := []struct {
+		name    string
+		rate    float64
+		sample  *QueryStats
+		verbose bool
+		levels  []LogLevel
+	}{
+		{"all calls at full rate", 1, &QueryStats{}, false, []LogLevel{LevelDebug}},
+		{"all calls at zero rate", 0, &QueryStats{}, false, []LogLevel{LevelDebug}},
+		{"sampled out calls", 0.0000001, &QueryStats{}, false, nil},
+		{"verbose calls", 0.0000001, &QueryStats{}, true, []LogLevel{LevelDebug}},
+		{"slow calls", 0.0000001, &QueryStats{ExecutionTime: time.Second}, false, []LogLevel{LevelWarning}},
+		{"failed calls", 0.0000001, &QueryStats{Err: ErrQueueTimeout}, false, []LogLevel{LevelError}},
+		{"slow logged calls", 1, &QueryStats{ExecutionTime: time.Second, slowLogged: true}, false, nil},
+	}
+
+	for _, test := range tests {
+		l := &recordingLogger{}
+
+		cfg := NewDefaultConfig()
+		cfg.Logger = l
+		cfg.LogLevel = LevelDebug
+		cfg.LogDebugSampleRate = test.rate
+		cfg.LogLatencyThreshold = time.Millisecond * 100
+
+		ctx := context.Background()
+		if test.verbose {
+			ctx = WithVerboseLogging(ctx)
+		}
+		logSample(ctx, cfg, test.sample)
+
+		if len(l.levels) != len(test.levels) || (len(l.levels) > 0 && l.levels[0] != test.levels[0]) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.levels, l.levels)
+		}
+	}
+}
+
+func TestLogEventArgsWithoutQuery(t *testing.T) {
+	err := errors.New("bad connection")
+	tests := []struct {
+		level LogLevel
+		event LogEvent
+		args  []interface{}
+	}{
+		{LevelWarning, LogEvent{Message: "config reload: new config applied"}, []interface{}{"config reload: new config applied"}},
+		{LevelWarning, LogEvent{Message: "KILL QUERY 5", Query: "SELECT 1"}, []interface{}{"KILL QUERY 5", "SELECT 1"}},
+		{LevelError, LogEvent{Message: "failover: no writable endpoint found"}, []interface{}{"failover: no writable endpoint found"}},
+		{LevelError, LogEvent{Message: "config reload", Err: err}, []interface{}{err, "config reload"}},
+		{LevelError, LogEvent{Message: "kill query", Query: "KILL QUERY 5", Err: err}, []interface{}{err, "KILL QUERY 5"}},
+	}
+
+	for _, test := range tests {
+		if args := test.event.args(test.level); !reflect.DeepEqual(args, test.args) {
+			t.Errorf("%q: expected %v, got %v", test.event.Message, test.args, args)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+// Package metrics exports client stats in the Prometheus text exposition format, without depending on the Prometheus client.
+//
+//  collector := metrics.NewCollector()
+//  collector.Register("users", client)
+//  http.Handle("/metrics", collector)
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	mysql "github.com/livechat/go-mysql"
+)
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+const namespace = "mysql_client_"
+
+// Collector collects stats of registered clients and their replicas.
+//
+type Collector struct {
+	mu      sync.Mutex
+	clients []namedClient
+}
+
+type namedClient struct {
+	name   string
+	client *mysql.Client
+}
+
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+// Register adds the client labelled with name, its replica is collected with replica role.
+//
+func (c *Collector) Register(name string, client *mysql.Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.clients = append(c.clients, namedClient{name, client})
+}
+
+// ServeHTTP writes metrics of all registered clients.
+//
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WriteTo(w)
+}
+
+// WriteTo writes metrics of all registered clients in the text exposition format.
+//
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	c.mu.Lock()
+	clients := make([]namedClient, len(c.clients))
+	copy(clients, c.clients)
+	c.mu.Unlock()
+
+	fs := newFamilies()
+	for _, nc := range clients {
+		fs.collect(nc.client.Stats(), label{"client", nc.name}, label{"role", string(mysql.RolePrimary)})
+
+		if replica := nc.client.Replica(); replica != nil {
+			fs.collect(replica.Stats(), label{"client", nc.name}, label{"role", string(mysql.RoleReplica)})
+		}
+	}
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	fs.write(cw)
+
+	if err := cw.w.Flush(); err != nil && cw.err == nil {
+		cw.err = err
+	}
+
+	return cw.n, cw.err
+}
+
+type label struct {
+	name, value string
+}
+
+type sample struct {
+	suffix string
+	labels []label
+	value  float64
+}
+
+type family struct {
+	name    string
+	help    string
+	typ     string
+	samples []sample
+}
+
+// families keeps metric families in order of registration, so samples of all clients are grouped by family.
+type families struct {
+	order []string
+	m     map[string]*family
+}
+
+func newFamilies() *families {
+	return &families{m: make(map[string]*family)}
+}
+
+func (fs *families) add(name, typ, help string, value float64, labels ...label) {
+	f := fs.family(name, typ, help)
+	f.samples = append(f.samples, sample{"", labels, value})
+}
+
+func (fs *families) family(name, typ, help string) *family {
+	f, found := fs.m[name]
+	if !found {
+		f = &family{name: namespace + name, help: help, typ: typ}
+		fs.m[name] = f
+		fs.order = append(fs.order, name)
+	}
+
+	return f
+}
+
+func (fs *families) histogram(name, help string, h mysql.Histogram, labels ...label) {
+	f := fs.family(name, "histogram", help)
+
+	for _, b := range h.Buckets {
+		le := label{"le", formatFloat(b.UpperBound)}
+		f.samples = append(f.samples, sample{"_bucket", append(append([]label{}, labels...), le), float64(b.Count)})
+	}
+
+	f.samples = append(f.samples, sample{"_sum", labels, h.Sum}, sample{"_count", labels, float64(h.Count)})
+}
+
+func (fs *families) collect(s *mysql.Stats, labels ...label) {
+	fs.add("in_progress_queries", "gauge", "Queries running or waiting for connection.", float64(s.InProgressQueries), labels...)
+	fs.add("success_queries_total", "counter", "Successful queries.", float64(s.TotalSuccessQueries), labels...)
+	fs.add("failed_queries_total", "counter", "Failed queries.", float64(s.TotalFailedQueries), labels...)
+	fs.add("killed_queries_total", "counter", "Queries killed on the server after context cancellation.", float64(s.TotalKilledQueries), labels...)
+	fs.add("concurrency_limit", "gauge", "Adaptive limit of in-flight queries, 0 if disabled.", float64(s.ConcurrencyLimit), labels...)
+	fs.add("circuit_state", "gauge", "Circuit breaker state: 0 closed, 1 open, 2 half-open.", float64(s.CircuitState), labels...)
+	fs.add("active_endpoint", "gauge", "Index of the endpoint writes go to.", float64(s.ActiveEndpoint), labels...)
+
+	numbers := make([]int, 0, len(s.Errors))
+	for number := range s.Errors {
+		numbers = append(numbers, int(number))
+	}
+	sort.Ints(numbers)
+
+	for _, number := range numbers {
+		fs.add("errors_total", "counter", "Failed queries by MySQL error number.", float64(s.Errors[uint16(number)]),
+			append(append([]label{}, labels...), label{"number", strconv.Itoa(number)})...)
+	}
+
+	fs.add("max_open_connections", "gauge", "Maximum number of open connections.", float64(s.MaxOpenConnections), labels...)
+	fs.add("open_connections", "gauge", "Established connections, in use and idle.", float64(s.OpenConnections), labels...)
+	fs.add("in_use_connections", "gauge", "Connections currently in use.", float64(s.InUse), labels...)
+	fs.add("idle_connections", "gauge", "Idle connections.", float64(s.Idle), labels...)
+	fs.add("wait_count_total", "counter", "Connections waited for.", float64(s.WaitCount), labels...)
+	fs.add("wait_duration_seconds_total", "counter", "Time blocked waiting for a new connection.", s.WaitDuration.Seconds(), labels...)
+	fs.add("max_idle_closed_total", "counter", "Connections closed due to MaxIdleConns.", float64(s.MaxIdleClosed), labels...)
+	fs.add("max_lifetime_closed_total", "counter", "Connections closed due to ConnMaxLifetime.", float64(s.MaxLifetimeClosed), labels...)
+
+	fs.histogram("queue_wait_seconds", "Time spent waiting for connection.", s.QueueWait, labels...)
+
+	operations := make([]string, 0, len(s.Latency))
+	for op := range s.Latency {
+		operations = append(operations, string(op))
+	}
+	sort.Strings(operations)
+
+	for _, op := range operations {
+		l := s.Latency[mysql.Operation(op)]
+		opLabels := append(append([]label{}, labels...), label{"operation", op})
+
+		fs.histogram("execution_time_seconds", "Query execution time.", l.ExecutionTime, opLabels...)
+		fs.histogram("queue_time_seconds", "Query queue time.", l.QueueTime, opLabels...)
+
+		if op == string(mysql.OperationCommit) || op == string(mysql.OperationRollback) {
+			fs.histogram("transaction_time_seconds", "Transaction time, observed on commit and rollback.", l.TransactionTime, opLabels...)
+		}
+	}
+}
+
+func (fs *families) write(w io.Writer) {
+	for _, name := range fs.order {
+		f := fs.m[name]
+
+		fmt.Fprintf(w, "# HELP %s %s\n", f.name, f.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.typ)
+
+		for _, s := range f.samples {
+			fmt.Fprintf(w, "%s%s%s %s\n", f.name, s.suffix, formatLabels(s.labels), formatFloat(s.value))
+		}
+	}
+}
+
+func formatLabels(labels []label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = l.name + `="` + labelEscaper.Replace(l.value) + `"`
+	}
+
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+
+	return n, err
+}
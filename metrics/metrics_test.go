@@ -0,0 +1,191 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	mysql "github.com/livechat/go-mysql"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func newTestClient(t *testing.T) *mysql.Client {
+	// the client doesn't connect until it's used
+	c, err := mysql.NewClient("user:password@tcp(127.0.0.1:1)/db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		c.Close(context.Background())
+	})
+
+	return c
+}
+
+func TestWriteTo(t *testing.T) {
+	users := newTestClient(t)
+	users.SetReplica(newTestClient(t))
+
+	collector := NewCollector()
+	collector.Register("users", users)
+	collector.Register("quoted \"name\" with \\ and\nnewline", newTestClient(t))
+
+	var buf bytes.Buffer
+	n, err := collector.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("expected %d bytes written, got %d", buf.Len(), n)
+	}
+
+	golden := filepath.Join("testdata", "writeto.golden")
+	if *update {
+		if err := ioutil.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Errorf("output differs from %s, run go test with -update if the change is expected:\n%s", golden, buf.String())
+	}
+
+	checkExposition(t, buf.String())
+
+	if !strings.Contains(buf.String(), `client="quoted \"name\" with \\ and\nnewline"`) {
+		t.Error("expected the client label value to be escaped")
+	}
+}
+
+func TestHistogramBucketsAreCumulative(t *testing.T) {
+	s := &mysql.Stats{
+		QueueWait: mysql.Histogram{
+			Buckets: []mysql.Bucket{{UpperBound: .01, Count: 2}, {UpperBound: .1, Count: 5}, {UpperBound: math.Inf(1), Count: 6}},
+			Count:   6,
+			Sum:     .75,
+		},
+	}
+
+	fs := newFamilies()
+	fs.collect(s, label{"client", "users"})
+
+	var buf bytes.Buffer
+	fs.write(&buf)
+
+	for _, line := range []string{
+		`mysql_client_queue_wait_seconds_bucket{client="users",le="0.01"} 2`,
+		`mysql_client_queue_wait_seconds_bucket{client="users",le="0.1"} 5`,
+		`mysql_client_queue_wait_seconds_bucket{client="users",le="+Inf"} 6`,
+		`mysql_client_queue_wait_seconds_sum{client="users"} 0.75`,
+		`mysql_client_queue_wait_seconds_count{client="users"} 6`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("expected %q in:\n%s", line, buf.String())
+		}
+	}
+
+	checkExposition(t, buf.String())
+}
+
+var (
+	sampleRegexp = regexp.MustCompile(`^(\w+?)(_bucket|_sum|_count)?(\{.*\})? (\S+)$`)
+	leRegexp     = regexp.MustCompile(`,le="([^"]*)"\}$`) // le is the last label of a bucket
+)
+
+// checkExposition checks that every family has a single HELP and TYPE line before its samples, and histogram
+// series have cumulative buckets ending with le="+Inf" followed by _sum and _count.
+func checkExposition(t *testing.T, out string) {
+	t.Helper()
+
+	var (
+		help, typ = map[string]int{}, map[string]int{}
+		types     = map[string]string{}
+		family    string
+		series    string // labels of the histogram series without le
+		last      float64
+		lastLE    string
+		suffixes  []string
+	)
+
+	checkSeries := func() {
+		if series == "" {
+			return
+		}
+		if lastLE != "+Inf" {
+			t.Errorf("%s%s: expected the last bucket to be le=\"+Inf\", got %q", family, series, lastLE)
+		}
+		if got := strings.Join(suffixes[len(suffixes)-2:], ","); got != "_sum,_count" {
+			t.Errorf("%s%s: expected buckets followed by _sum and _count, got %s", family, series, got)
+		}
+		series, last, lastLE, suffixes = "", 0, "", nil
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 4 && fields[0] == "#" {
+			checkSeries()
+			family = fields[2]
+			switch fields[1] {
+			case "HELP":
+				help[family]++
+			case "TYPE":
+				typ[family]++
+				types[family] = fields[3]
+			}
+			continue
+		}
+
+		m := sampleRegexp.FindStringSubmatch(line)
+		if m == nil {
+			t.Fatalf("malformed sample %q", line)
+		}
+		if m[1] != family {
+			t.Errorf("sample %q isn't preceded by its family HELP and TYPE", line)
+		}
+
+		if types[family] != "histogram" {
+			continue
+		}
+
+		labels := leRegexp.ReplaceAllString(m[3], "}")
+		if labels != series && m[2] == "_bucket" {
+			checkSeries()
+			series = labels
+		}
+		suffixes = append(suffixes, m[2])
+
+		if m[2] == "_bucket" {
+			value, err := strconv.ParseFloat(m[4], 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if value < last {
+				t.Errorf("%q: bucket count is lower than the previous one", line)
+			}
+			le := leRegexp.FindStringSubmatch(m[3])
+			if le == nil {
+				t.Fatalf("%q: bucket without le label", line)
+			}
+			last, lastLE = value, le[1]
+		}
+	}
+	checkSeries()
+
+	for name := range types {
+		if help[name] != 1 || typ[name] != 1 {
+			t.Errorf("%s: expected a single HELP and TYPE, got %d and %d", name, help[name], typ[name])
+		}
+	}
+}
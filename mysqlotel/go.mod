@@ -0,0 +1,17 @@
+module github.com/livechat/go-mysql/mysqlotel
+
+go 1.20
+
+// go-mysql is replaced with the parent directory in go.work during development
+require (
+	github.com/livechat/go-mysql v1.0.0
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.1 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/go-sql-driver/mysql v1.7.1 // indirect
+	go.opentelemetry.io/otel/metric v1.24.0 // indirect
+)
@@ -0,0 +1,128 @@
+// Package mysqlotel implements mysql.Tracer with OpenTelemetry.
+//
+//  cfg := mysql.NewDefaultConfig()
+//  cfg.Tracer = mysqlotel.NewTracer(nil)
+//  client.SetConfig(cfg)
+//
+// Spans are started as children of spans found in the context passed to Client and Transaction methods.
+// They're named after the SQL command, like SELECT or COMMIT, and have db.* attributes set.
+package mysqlotel
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	mysql "github.com/livechat/go-mysql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/livechat/go-mysql"
+
+const (
+	dbSystem        = attribute.Key("db.system")
+	dbStatement     = attribute.Key("db.statement")
+	dbOperation     = attribute.Key("db.operation")
+	dbRowsReturned  = attribute.Key("db.mysql.rows_returned")
+	dbRowsAffected  = attribute.Key("db.mysql.rows_affected")
+	dbErrorNumber   = attribute.Key("db.mysql.error_number")
+	dbAttempts      = attribute.Key("db.mysql.attempts")
+	dbRole          = attribute.Key("db.mysql.role")
+	dbTransactionID = attribute.Key("db.mysql.transaction_id")
+	dbQueueTime     = attribute.Key("db.mysql.queue_time_ms")
+)
+
+type tracer struct {
+	t trace.Tracer
+}
+
+// NewTracer returns a tracer creating spans with the provider, the global provider is used if tp is nil.
+//
+func NewTracer(tp trace.TracerProvider) mysql.Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+
+	return &tracer{tp.Tracer(instrumentationName)}
+}
+
+func (t *tracer) StartSpan(ctx context.Context, operation mysql.Operation, query string) (context.Context, mysql.Span) {
+	command := command(query)
+	ctx, span := t.t.Start(ctx, command,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			dbSystem.String("mysql"),
+			dbStatement.String(query),
+			dbOperation.String(command),
+		),
+	)
+
+	return ctx, &otelSpan{span}
+}
+
+// TraceParent returns W3C traceparent of the span found in ctx, it's added to sql comments
+// when Config.SQLCommentTraceParent is enabled.
+//
+func (t *tracer) TraceParent(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ""
+	}
+
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID(), sc.SpanID(), sc.TraceFlags())
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s *otelSpan) End(sample *mysql.QueryStats) {
+	s.span.SetAttributes(
+		dbAttempts.Int(sample.Attempts),
+		dbRole.String(string(sample.Role)),
+		dbQueueTime.Float64(float64(sample.QueueTime.Microseconds())/1000),
+	)
+
+	if sample.TransactionID != 0 {
+		s.span.SetAttributes(dbTransactionID.Int64(int64(sample.TransactionID)))
+	}
+
+	switch sample.Operation {
+	case mysql.OperationQuery:
+		s.span.SetAttributes(dbRowsReturned.Int64(sample.RowsReturned))
+	case mysql.OperationExec:
+		s.span.SetAttributes(dbRowsAffected.Int64(sample.RowsAffected))
+	}
+
+	if sample.Err != nil {
+		if sample.ErrorNumber != 0 {
+			s.span.SetAttributes(dbErrorNumber.Int(int(sample.ErrorNumber)))
+		}
+		s.span.RecordError(sample.Err)
+		s.span.SetStatus(codes.Error, sample.Err.Error())
+	}
+
+	s.span.End()
+}
+
+// command returns the first keyword of the query in upper case, comments are skipped.
+func command(query string) string {
+	query = strings.TrimSpace(query)
+	for strings.HasPrefix(query, "/*") {
+		end := strings.Index(query, "*/")
+		if end < 0 {
+			return "SQL"
+		}
+		query = strings.TrimSpace(query[end+2:])
+	}
+
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "SQL"
+	}
+
+	return strings.ToUpper(strings.TrimRight(fields[0], ";("))
+}
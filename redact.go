@@ -0,0 +1,189 @@
+package mysql
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	mysql "github.com/go-sql-driver/mysql"
+)
+
+var (
+	redactInsert     = regexp.MustCompile(`(?is)^\s*(?:insert|replace)\s+(?:(?:low_priority|delayed|high_priority|ignore)\s+)*(?:into\s+)?[^\s(]+\s*\(([^)]*)\)\s*values?\s*\(`)
+	redactComparison = regexp.MustCompile("(?is)([`\\w.]+)\\s*(?:<=>|<>|!=|<=|>=|=|<|>|\\s(?:not\\s+)?like|\\s(?:not\\s+)?in\\s*\\((?:\\s*\\?\\s*,)*)\\s*$")
+)
+
+// redactQuery masks literals in the query text, according to Config.RedactLiterals and Config.RedactPatterns,
+// so it can be logged and reported safely.
+func redactQuery(cfg *Config, query string) string {
+	if cfg.RedactLiterals {
+		query = fingerprintStrings.ReplaceAllString(query, "?")
+		query = fingerprintNumbers.ReplaceAllString(query, "?")
+	}
+
+	for _, pattern := range cfg.RedactPatterns {
+		query = pattern.ReplaceAllString(query, "?")
+	}
+
+	return query
+}
+
+// redactError returns the text of a driver error which can be logged and reported safely. The message of a mysql
+// error is redacted like a query, so values quoted by the server, like a duplicate entry, are masked.
+// Other errors are redacted only with Config.RedactPatterns.
+func redactError(cfg *Config, err error) string {
+	text := err.Error()
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Message != "" {
+		return strings.Replace(text, mysqlErr.Message, redactQuery(cfg, mysqlErr.Message), 1)
+	}
+
+	for _, pattern := range cfg.RedactPatterns {
+		text = pattern.ReplaceAllString(text, "?")
+	}
+
+	return text
+}
+
+// redactArgs replaces argument values with their types, so they can be logged safely.
+// Values of args bound to columns allowed with Config.LogArgs are kept.
+func redactArgs(allowed []string, query string, args []interface{}) []string {
+	if len(args) == 0 {
+		return nil
+	}
+
+	var columns []string
+	if len(allowed) > 0 {
+		columns = placeholderColumns(query)
+	}
+
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		switch {
+		case arg == nil:
+			redacted[i] = "NULL"
+		case i < len(columns) && isAllowed(allowed, columns[i]):
+			redacted[i] = formatArg(arg)
+		default:
+			redacted[i] = fmt.Sprintf("%T", arg)
+		}
+	}
+
+	return redacted
+}
+
+// placeholderColumns returns the column of every ? placeholder in the query, an empty string if it's unknown.
+// Columns are found in comparisons, like `id = ?` or `id IN (?, ?)`, and in INSERT and REPLACE column lists.
+func placeholderColumns(query string) []string {
+	// literals are blanked out, so ? inside them and their contents aren't matched
+	q := fingerprintStrings.ReplaceAllStringFunc(query, func(s string) string {
+		return strings.Repeat(" ", len(s))
+	})
+
+	var (
+		columns                []string
+		values                 map[int]string
+		valuesStart, valuesEnd int
+	)
+
+	if m := redactInsert.FindStringSubmatchIndex(q); m != nil {
+		var insert []string
+		for _, column := range strings.Split(q[m[2]:m[3]], ",") {
+			insert = append(insert, strings.TrimSpace(column))
+		}
+		valuesStart = m[1]
+		values, valuesEnd = valuesColumns(q, valuesStart, insert)
+	}
+
+	for i := strings.IndexByte(q, '?'); i >= 0; i = nextPlaceholder(q, i) {
+		if i >= valuesStart && i < valuesEnd {
+			columns = append(columns, values[i])
+			continue
+		}
+
+		if m := redactComparison.FindStringSubmatch(q[:i]); m != nil {
+			columns = append(columns, columnName(m[1]))
+			continue
+		}
+
+		columns = append(columns, "")
+	}
+
+	return columns
+}
+
+// valuesColumns maps offsets of placeholders in VALUES tuples, starting just after the opening parenthesis
+// of the first tuple, to insert columns by their position in a tuple. Placeholders nested in function calls
+// aren't mapped. It returns the offset of the end of the last tuple too, so placeholders after it,
+// like in ON DUPLICATE KEY UPDATE, are matched as comparisons.
+func valuesColumns(q string, offset int, insert []string) (map[int]string, int) {
+	columns := make(map[int]string)
+
+	depth, column := 1, 0
+	for i := offset; i < len(q); i++ {
+		switch q[i] {
+		case '(':
+			depth++
+			if depth == 1 {
+				column = 0
+			}
+		case ')':
+			depth--
+			if depth == 0 {
+				rest := strings.TrimLeft(q[i+1:], " \t\r\n")
+				if !strings.HasPrefix(rest, ",") || !strings.HasPrefix(strings.TrimLeft(rest[1:], " \t\r\n"), "(") {
+					return columns, i + 1
+				}
+			}
+		case ',':
+			if depth == 1 {
+				column++
+			}
+		case '?':
+			if depth == 1 && column < len(insert) {
+				columns[i] = columnName(insert[column])
+			}
+		}
+	}
+
+	return columns, len(q)
+}
+
+func nextPlaceholder(q string, i int) int {
+	next := strings.IndexByte(q[i+1:], '?')
+	if next < 0 {
+		return -1
+	}
+
+	return i + 1 + next
+}
+
+// columnName strips quotes and the table of a column.
+func columnName(column string) string {
+	column = strings.Replace(column, "`", "", -1)
+	if i := strings.LastIndexByte(column, '.'); i >= 0 {
+		column = column[i+1:]
+	}
+
+	return column
+}
+
+func isAllowed(allowed []string, column string) bool {
+	for _, a := range allowed {
+		if strings.EqualFold(a, column) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func formatArg(arg interface{}) string {
+	if b, ok := arg.([]byte); ok {
+		return string(b)
+	}
+
+	return fmt.Sprint(arg)
+}
@@ -0,0 +1,122 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	mysql "github.com/go-sql-driver/mysql"
+)
+
+func TestPlaceholderColumns(t *testing.T) {
+	tests := []struct {
+		query   string
+		columns []string
+	}{
+		{"SELECT * FROM `foo` WHERE `id` = ? AND name LIKE ?", []string{"id", "name"}},
+		{"SELECT * FROM foo WHERE f.id IN (?, ?) AND x <> ?", []string{"id", "id", "x"}},
+		{"SELECT * FROM foo WHERE note = '?' AND id = ?", []string{"id"}},
+		{"INSERT INTO foo (id, name) VALUES (?, ?)", []string{"id", "name"}},
+		{"INSERT INTO foo (id, name) VALUES (?, ?), (?, ?)", []string{"id", "name", "id", "name"}},
+		{"INSERT INTO foo (id, name) VALUES (?, LOWER(?))", []string{"id", ""}},
+		{"INSERT INTO foo (id, name) VALUES (?, ?) ON DUPLICATE KEY UPDATE email = ?", []string{"id", "name", "email"}},
+		{"insert into foo (id, name) values (?, ?), (?, ?) on duplicate key update note = concat(note, ?)", []string{"id", "name", "id", "name", ""}},
+		{"REPLACE foo (`id`) VALUE (?)", []string{"id"}},
+		{"UPDATE foo SET a = 1 LIMIT ?", []string{""}},
+	}
+
+	for _, test := range tests {
+		if columns := placeholderColumns(test.query); !reflect.DeepEqual(columns, test.columns) {
+			t.Errorf("%s: expected %q, got %q", test.query, test.columns, columns)
+		}
+	}
+}
+
+func TestRedactArgsKeepsOnlyAllowedColumns(t *testing.T) {
+	query := "INSERT INTO users (id, name) VALUES (?, ?) ON DUPLICATE KEY UPDATE email = ?"
+
+	redacted := redactArgs([]string{"id", "name"}, query, []interface{}{int64(1), "bob", "bob@example.com"})
+	if expected := []string{"1", "bob", "string"}; !reflect.DeepEqual(redacted, expected) {
+		t.Errorf("expected %q, got %q", expected, redacted)
+	}
+}
+
+func TestRedactQuery(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.RedactLiterals = true
+
+	tests := []struct {
+		query    string
+		redacted string
+	}{
+		{"SELECT * FROM foo WHERE name = 'bob' AND id = 12", "SELECT * FROM foo WHERE name = ? AND id = ?"},
+		{"SELECT * FROM foo WHERE hash = 0xDEADBEEF", "SELECT * FROM foo WHERE hash = ?"},
+		{"SELECT * FROM foo WHERE x = 1.5E3 AND y = \"it\"\"s\"", "SELECT * FROM foo WHERE x = ? AND y = ?"},
+		{"SELECT * FROM t1 WHERE id = ?", "SELECT * FROM t1 WHERE id = ?"},
+	}
+
+	for _, test := range tests {
+		if redacted := redactQuery(cfg, test.query); redacted != test.redacted {
+			t.Errorf("%s: expected %q, got %q", test.query, test.redacted, redacted)
+		}
+	}
+}
+
+func TestQueryErrorIsRedacted(t *testing.T) {
+	d := &fakeDriver{handler: func(query string) error {
+		return &mysql.MySQLError{Number: ErrMySQLDupEntry, Message: "Duplicate entry 'secret@pii.com' for key 'email'"}
+	}}
+	c := newFakeClient(t, d, func(cfg *Config) {
+		cfg.RedactLiterals = true
+	})
+
+	sub := c.Subscribe(1, OverflowDrop)
+	defer sub.Close()
+
+	_, err := c.Exec(context.Background(), "INSERT INTO u (email) VALUES (?)", "secret@pii.com")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("expected the value to be redacted, got %q", err.Error())
+	}
+	if !strings.HasPrefix(err.Error(), "Error 1062: Duplicate entry ? for key ?") {
+		t.Errorf("expected the redacted driver message, got %q", err.Error())
+	}
+	if sample := <-sub.C(); strings.Contains(sample.Err.Error(), "secret") {
+		t.Errorf("expected the sample error to be redacted, got %q", sample.Err.Error())
+	}
+
+	// the driver error is kept intact for callers
+	if dup, ok := ParseDupEntry(err); !ok || dup.Value != "secret@pii.com" {
+		t.Errorf("expected the duplicated value, got %+v", dup)
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.RedactPatterns = []*regexp.Regexp{regexp.MustCompile(`token=\w+`)}
+
+	tests := []struct {
+		literals bool
+		err      error
+		redacted string
+	}{
+		{false, &mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1' for key 'PRIMARY'"}, "Error 1062: Duplicate entry '1' for key 'PRIMARY'"},
+		{true, &mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1' for key 'PRIMARY'"}, "Error 1062: Duplicate entry ? for key ?"},
+		{true, fmt.Errorf("wrapped: %w", &mysql.MySQLError{Number: 1406, Message: "Data too long for column 'name' at row 1"}), "wrapped: Error 1406: Data too long for column ? at row ?"},
+		{true, errors.New("dial tcp 10.0.0.1:3306: token=abc"), "dial tcp 10.0.0.1:3306: ?"},
+	}
+
+	for _, test := range tests {
+		cfg.RedactLiterals = test.literals
+		if redacted := redactError(cfg, test.err); redacted != test.redacted {
+			t.Errorf("%v: expected %q, got %q", test.err, test.redacted, redacted)
+		}
+	}
+}
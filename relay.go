@@ -1,30 +1,316 @@
 package mysql
 
-import "time"
+import (
+	"context"
+	"sync"
+	"time"
+)
 
+// Priority is a class of queries sharing a lane in the relay queue.
+//
+type Priority string
+
+const (
+	PriorityInteractive Priority = "interactive" // user facing queries, the default
+	PriorityBackground  Priority = "background"  // batch jobs, workers, etc.
+)
+
+const priorityKey contextKey = "priority"
+
+// WithPriority returns a context with queries prioritized by p when they wait for a connection.
+//
+//  res, err := client.Query(mysql.WithPriority(ctx, mysql.PriorityBackground), "SELECT * FROM `foo`;")
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey, p)
+}
+
+func priorityFromCtx(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey).(Priority); ok {
+		return p
+	}
+
+	return PriorityInteractive
+}
+
+// relay limits the number of queries running at once, waiting queries are admitted from priority lanes
+// using smooth weighted round robin. Within a lane tenants are served in turns.
 type relay struct {
-	relayChan chan bool
+	mu      sync.Mutex
+	size    int // if size <= 0 then there is no limit
+	used    int
+	waiting int
+	maxWait time.Duration // if d <= 0 then queries wait until their context is done
+	weights map[Priority]int
+	lanes   map[Priority]*lane
+
+	queueWait   *histogram
+	queueLength *histogram
 }
 
+type lane struct {
+	weight  int
+	current int
+	waiting int
+	queues  map[string][]chan struct{} // waiting queries by tenant
+	tenants []string                   // tenants with waiting queries in order of service
+
+	admitted       int64
+	totalQueueTime time.Duration
+}
+
+func newRelay() *relay {
+	return &relay{
+		lanes:       make(map[Priority]*lane),
+		queueWait:   newHistogram(durationBuckets),
+		queueLength: newHistogram(countBuckets),
+	}
+}
+
+// setRelaySize changes the relay capacity, slots already held are kept.
 func (t *relay) setRelaySize(size int) {
-	t.relayChan = make(chan bool, size)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.size = size
+	for t.waiting > 0 && t.free() {
+		t.used++
+		t.admit()
+	}
+}
+
+// setWeights sets admission weights of priority lanes, lanes without weight get 1.
+func (t *relay) setWeights(weights map[Priority]int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.weights = weights
+	for p, l := range t.lanes {
+		l.weight = t.weight(p)
+	}
+}
+
+// setMaxWait sets a limit of time spent in queue, if d <= 0 then queries wait until their context is done.
+func (t *relay) setMaxWait(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.maxWait = d
 }
 
-func (t *relay) start() time.Duration {
+// start waits for a free slot. It returns ErrQueueTimeout when the slot isn't acquired within max wait time
+// or the context error if the context is done first.
+func (t *relay) start(ctx context.Context) (time.Duration, error) {
 	s := time.Now()
-	t.relayChan <- true
-	return time.Now().Sub(s)
+	l := t.lane(priorityFromCtx(ctx))
+	tenant := tenantFromCtx(ctx)
+
+	t.mu.Lock()
+	t.queueLength.observe(float64(t.waiting))
+
+	if t.waiting == 0 && t.free() {
+		t.used++
+		l.admitted++
+		t.mu.Unlock()
+		t.queueWait.observe(0)
+		return 0, nil
+	}
+
+	ready := make(chan struct{})
+	l.push(tenant, ready)
+	t.waiting++
+	maxWait := t.maxWait
+	t.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if maxWait > 0 {
+		timer := time.NewTimer(maxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+
+	select {
+	case <-ready:
+	case <-timeout:
+		err = ErrQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	queueTime := time.Now().Sub(s)
+	t.queueWait.observe(queueTime.Seconds())
+
+	if err != nil {
+		t.mu.Lock()
+		removed := l.remove(tenant, ready)
+		if removed {
+			t.waiting--
+		}
+		t.mu.Unlock()
+
+		if !removed {
+			// the slot was acquired in the meantime, it has to be passed on
+			t.end()
+		}
+
+		return queueTime, err
+	}
+
+	t.mu.Lock()
+	l.totalQueueTime += queueTime
+	t.mu.Unlock()
+
+	return queueTime, nil
 }
 
 func (t *relay) end() {
-	select {
-	case <-t.relayChan:
-	default:
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.waiting > 0 && (t.size <= 0 || t.used <= t.size) {
+		// the slot is handed over to the next waiting query
+		t.admit()
+		return
+	}
+
+	if t.used > 0 {
+		t.used--
 	}
 }
 
 func (t *relay) conditionalEnd(err *error) {
-	if err != nil {
+	if *err != nil {
 		t.end()
 	}
 }
+
+func (t *relay) free() bool {
+	return t.size <= 0 || t.used < t.size
+}
+
+// admit wakes up the first query from the lane chosen by smooth weighted round robin, t.mu must be held.
+func (t *relay) admit() {
+	var (
+		selected *lane
+		total    int
+	)
+
+	for _, l := range t.lanes {
+		if l.waiting == 0 {
+			continue
+		}
+
+		l.current += l.weight
+		total += l.weight
+
+		if selected == nil || l.current > selected.current {
+			selected = l
+		}
+	}
+
+	if selected == nil {
+		return
+	}
+
+	selected.current -= total
+
+	ready := selected.pop()
+	selected.admitted++
+	t.waiting--
+
+	close(ready)
+}
+
+func (l *lane) push(tenant string, ready chan struct{}) {
+	if len(l.queues[tenant]) == 0 {
+		l.tenants = append(l.tenants, tenant)
+	}
+
+	l.queues[tenant] = append(l.queues[tenant], ready)
+	l.waiting++
+}
+
+// pop returns the first query of the tenant in turn, the tenant goes to the end of the line.
+func (l *lane) pop() chan struct{} {
+	tenant := l.tenants[0]
+	l.tenants = l.tenants[1:]
+
+	queue := l.queues[tenant]
+	ready := queue[0]
+
+	if len(queue) > 1 {
+		l.queues[tenant] = queue[1:]
+		l.tenants = append(l.tenants, tenant)
+	} else {
+		delete(l.queues, tenant)
+	}
+
+	l.waiting--
+	return ready
+}
+
+// remove removes a waiting query, it returns false if the query isn't waiting anymore.
+func (l *lane) remove(tenant string, ready chan struct{}) bool {
+	queue := l.queues[tenant]
+
+	for i, r := range queue {
+		if r != ready {
+			continue
+		}
+
+		if len(queue) == 1 {
+			delete(l.queues, tenant)
+			for j, t := range l.tenants {
+				if t == tenant {
+					l.tenants = append(l.tenants[:j], l.tenants[j+1:]...)
+					break
+				}
+			}
+		} else {
+			l.queues[tenant] = append(queue[:i], queue[i+1:]...)
+		}
+
+		l.waiting--
+		return true
+	}
+
+	return false
+}
+
+func (t *relay) lane(p Priority) *lane {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	l, found := t.lanes[p]
+	if !found {
+		l = &lane{weight: t.weight(p), queues: make(map[string][]chan struct{})}
+		t.lanes[p] = l
+	}
+
+	return l
+}
+
+func (t *relay) weight(p Priority) int {
+	if w := t.weights[p]; w > 0 {
+		return w
+	}
+
+	return 1
+}
+
+func (t *relay) stats() map[Priority]PriorityStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := make(map[Priority]PriorityStats, len(t.lanes))
+	for p, l := range t.lanes {
+		s[p] = PriorityStats{
+			Queued:         int64(l.waiting),
+			Admitted:       l.admitted,
+			TotalQueueTime: l.totalQueueTime,
+		}
+	}
+
+	return s
+}
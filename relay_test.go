@@ -0,0 +1,215 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// queue starts a waiting call for every context, one by one, so they're queued in order.
+// Names of admitted calls are sent to the returned channel.
+func queue(t *testing.T, r *relay, ctxs []context.Context, names []string) <-chan string {
+	admitted := make(chan string, len(ctxs))
+
+	for i, ctx := range ctxs {
+		go func(ctx context.Context, name string) {
+			if _, err := r.start(ctx); err != nil {
+				t.Error(err)
+				return
+			}
+			admitted <- name
+		}(ctx, names[i])
+
+		waitFor(t, func() bool {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+
+			return r.waiting == i+1
+		})
+	}
+
+	return admitted
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// admitAll hands the held slot over to every waiting call in turn and returns their names in order of admission.
+func admitAll(r *relay, admitted <-chan string, n int) []string {
+	var order []string
+	for i := 0; i < n; i++ {
+		r.end()
+		order = append(order, <-admitted)
+	}
+
+	return order
+}
+
+func TestRelayAdmitsLanesByWeight(t *testing.T) {
+	r := newRelay()
+	r.setRelaySize(1)
+	r.setWeights(map[Priority]int{PriorityInteractive: 3, PriorityBackground: 1})
+
+	if _, err := r.start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		ctxs  []context.Context
+		names []string
+	)
+	for i := 0; i < 4; i++ {
+		ctxs = append(ctxs, WithPriority(context.Background(), PriorityBackground))
+		names = append(names, "b")
+	}
+	for i := 0; i < 4; i++ {
+		ctxs = append(ctxs, WithPriority(context.Background(), PriorityInteractive))
+		names = append(names, "i")
+	}
+
+	// every round of the total weight admits calls in proportion to lane weights, ties are broken in any order
+	order := admitAll(r, queue(t, r, ctxs, names), len(ctxs))
+	interactive := 0
+	for _, name := range order[:4] {
+		if name == "i" {
+			interactive++
+		}
+	}
+	if interactive != 3 {
+		t.Errorf("expected 3 interactive calls in the first round, got %v", order)
+	}
+
+	stats := r.stats()
+	if s := stats[PriorityBackground]; s.Admitted != 4 || s.Queued != 0 {
+		t.Errorf("unexpected background stats %+v", s)
+	}
+}
+
+func TestRelayServesTenantsInTurns(t *testing.T) {
+	r := newRelay()
+	r.setRelaySize(1)
+
+	if _, err := r.start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	tenants := []string{"a", "a", "a", "b", "c"}
+	ctxs := make([]context.Context, len(tenants))
+	for i, tenant := range tenants {
+		ctxs[i] = WithTenant(context.Background(), tenant)
+	}
+
+	order := admitAll(r, queue(t, r, ctxs, tenants), len(ctxs))
+	if expected := []string{"a", "b", "c", "a", "a"}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("expected %v, got %v", expected, order)
+	}
+}
+
+func TestRelayMaxWait(t *testing.T) {
+	r := newRelay()
+	r.setRelaySize(1)
+	r.setMaxWait(time.Millisecond * 20)
+
+	if _, err := r.start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	queueTime, err := r.start(context.Background())
+	if !errors.Is(err, ErrQueueTimeout) {
+		t.Fatalf("expected ErrQueueTimeout, got %v", err)
+	}
+	if queueTime < time.Millisecond*20 {
+		t.Errorf("expected queue time of at least 20ms, got %s", queueTime)
+	}
+
+	// the timed out call doesn't take the slot released afterwards
+	r.end()
+	if _, err := r.start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRelayResizeKeepsHeldSlots(t *testing.T) {
+	r := newRelay()
+	r.setRelaySize(2)
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r.setRelaySize(1)
+	admitted := queue(t, r, []context.Context{context.Background()}, []string{"waiting"})
+
+	// the first release only gives back the slot above the new size
+	r.end()
+	select {
+	case <-admitted:
+		t.Fatal("expected the call to wait")
+	case <-time.After(time.Millisecond * 20):
+	}
+
+	r.end()
+	<-admitted
+}
+
+func TestInteractiveCallsSkipQueuedBackgroundCalls(t *testing.T) {
+	d := &fakeDriver{delay: time.Millisecond * 50}
+	c := newFakeClient(t, d, func(cfg *Config) {
+		cfg.MaxOpenConns = 2
+		cfg.PriorityWeights = map[Priority]int{PriorityInteractive: 4, PriorityBackground: 1}
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Exec(WithPriority(context.Background(), PriorityBackground), "UPDATE `jobs` SET `done` = 1"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	defer wg.Wait()
+
+	waitFor(t, func() bool {
+		return c.Stats().Priorities[PriorityBackground].Queued == 18
+	})
+
+	// background calls need 500ms to finish, an interactive call is admitted with the first released connection
+	start := time.Now()
+	if _, err := c.Exec(context.Background(), "UPDATE `foo` SET `bar` = 1"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Millisecond*250 {
+		t.Errorf("expected the interactive call to skip the background queue, it took %s", elapsed)
+	}
+}
+
+func TestRelaySize(t *testing.T) {
+	cfg := NewDefaultConfig()
+	if size := relaySize(cfg); size != cfg.MaxOpenConns*10 {
+		t.Errorf("got relay size %d with default config, want %d", size, cfg.MaxOpenConns*10)
+	}
+
+	cfg.PriorityWeights = map[Priority]int{PriorityInteractive: 4}
+	if size := relaySize(cfg); size != cfg.MaxOpenConns {
+		t.Errorf("got relay size %d with priority lanes, want %d", size, cfg.MaxOpenConns)
+	}
+
+	cfg.PriorityWeights, cfg.MaxQueueWait = nil, time.Second
+	if size := relaySize(cfg); size != cfg.MaxOpenConns {
+		t.Errorf("got relay size %d with MaxQueueWait, want %d", size, cfg.MaxOpenConns)
+	}
+}
@@ -0,0 +1,123 @@
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ConfigLoader returns a config to be applied by WatchConfig. It gets a copy of the current config,
+// which can be modified and returned.
+//
+type ConfigLoader func(current *Config) (*Config, error)
+
+// ConfigFile returns a loader reading the config from a JSON file with Config field names as keys.
+// Fields missing in the file keep their current values, durations are strings like "100ms" or numbers of nanoseconds.
+// Fields which can't be serialized, like Logger or Tracer, can't be set in the file.
+//
+//  {"MaxOpenConns": 20, "Timeout": "5s", "LogLevel": "warning"}
+func ConfigFile(path string) ConfigLoader {
+	return func(current *Config) (*Config, error) {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := decodeConfig(data, current); err != nil {
+			return nil, fmt.Errorf("config file %s: %w", path, err)
+		}
+
+		return current, nil
+	}
+}
+
+// WatchConfig calls load every interval and applies the returned config with SetConfig when it differs from
+// the current one. Errors returned by load are logged and the current config is kept.
+// It blocks until ctx is done and returns ctx error, or returns an error right away if interval isn't positive.
+//
+//  go client.WatchConfig(ctx, time.Minute, mysql.ConfigFile("/etc/app/mysql.json"))
+func (c *Client) WatchConfig(ctx context.Context, interval time.Duration, load ConfigLoader) error {
+	if interval <= 0 {
+		return fmt.Errorf("config watch interval must be positive, got %s", interval)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		c.reloadConfig(ctx, load)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (c *Client) reloadConfig(ctx context.Context, load ConfigLoader) {
+	current := c.Config()
+
+	cfg, err := load(c.Config())
+	if err != nil {
+		logEvent(ctx, current, LevelError, LogEvent{Message: "config reload", Err: err})
+		return
+	}
+
+	if cfg == nil || reflect.DeepEqual(cfg.clone(), current) {
+		return
+	}
+
+	c.SetConfig(cfg)
+
+	logEvent(ctx, cfg, LevelWarning, LogEvent{Message: "config reload: new config applied"})
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// decodeConfig sets fields of cfg found in the JSON object.
+func decodeConfig(data []byte, cfg *Config) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	v := reflect.ValueOf(cfg).Elem()
+	for name, raw := range fields {
+		field, found := v.Type().FieldByNameFunc(func(n string) bool {
+			return strings.EqualFold(n, name)
+		})
+		if !found {
+			return fmt.Errorf("unknown field %s", name)
+		}
+
+		value := v.FieldByIndex(field.Index)
+		switch {
+		case field.Type.Kind() == reflect.Interface:
+			return fmt.Errorf("field %s can't be set in a file", field.Name)
+		case field.Type == durationType && bytes.HasPrefix(bytes.TrimSpace(raw), []byte(`"`)):
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return fmt.Errorf("field %s: %w", field.Name, err)
+			}
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", field.Name, err)
+			}
+			value.SetInt(int64(d))
+		default:
+			// maps and slices are shared with the current config, they're replaced instead of being decoded into
+			value.Set(reflect.Zero(field.Type))
+			if err := json.Unmarshal(raw, value.Addr().Interface()); err != nil {
+				return fmt.Errorf("field %s: %w", field.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchConfigRejectsInvalidInterval(t *testing.T) {
+	c := newFakeClient(t, &fakeDriver{}, nil)
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		err := c.WatchConfig(context.Background(), interval, func(current *Config) (*Config, error) {
+			return current, nil
+		})
+		if err == nil {
+			t.Errorf("interval %s: expected an error", interval)
+		}
+	}
+}
+
+func TestWatchConfigAppliesLoadedConfig(t *testing.T) {
+	c := newFakeClient(t, &fakeDriver{}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error)
+	go func() {
+		done <- c.WatchConfig(ctx, time.Millisecond*10, func(current *Config) (*Config, error) {
+			current.MaxOpenConns = 7
+			return current, nil
+		})
+	}()
+
+	waitFor(t, func() bool {
+		return c.Config().MaxOpenConns == 7
+	})
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestConfigFile(t *testing.T) {
+	tests := []struct {
+		content string
+		check   func(cfg *Config) bool
+		failed  bool
+	}{
+		{`{"MaxOpenConns": 20, "Timeout": "5s", "LogLevel": "warning"}`, func(cfg *Config) bool {
+			return cfg.MaxOpenConns == 20 && cfg.Timeout == time.Second*5 && cfg.LogLevel == LevelWarning
+		}, false},
+		{`{"maxqueuewait": 1000000}`, func(cfg *Config) bool {
+			return cfg.MaxQueueWait == time.Millisecond
+		}, false},
+		{`{"PriorityWeights": {"background": 2}}`, func(cfg *Config) bool {
+			return len(cfg.PriorityWeights) == 1 && cfg.PriorityWeights[PriorityBackground] == 2
+		}, false},
+		{`{"Unknown": 1}`, nil, true},
+		{`{"Logger": null}`, nil, true},
+		{`{"Timeout": "soon"}`, nil, true},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "mysql.json")
+		if err := ioutil.WriteFile(path, []byte(test.content), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		cfg, err := ConfigFile(path)(NewDefaultConfig())
+		if (err != nil) != test.failed {
+			t.Errorf("%s: expected failure %v, got %v", test.content, test.failed, err)
+			continue
+		}
+		if err == nil && !test.check(cfg) {
+			t.Errorf("%s: unexpected config %+v", test.content, cfg)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+//go:build go1.21
+// +build go1.21
+
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+type slogLogger struct {
+	l   *slog.Logger
+	ctx context.Context
+}
+
+// NewSlogLogger returns a StructuredLogger writing LogEvents as slog records with key-value attributes.
+// Warning is logged with slog.LevelWarn.
+//
+//  mysql.SetLogger(mysql.NewSlogLogger(slog.Default()))
+func NewSlogLogger(l *slog.Logger) StructuredLogger {
+	return &slogLogger{l, context.Background()}
+}
+
+func (s *slogLogger) FromCtx(ctx context.Context) Logger {
+	return &slogLogger{s.l, ctx}
+}
+
+func (s *slogLogger) Tag(tag string) Logger {
+	return &slogLogger{s.l.With(slog.String("tag", tag)), s.ctx}
+}
+
+func (s *slogLogger) Error(p ...interface{}) {
+	s.l.Log(s.ctx, slog.LevelError, sprint(p))
+}
+
+func (s *slogLogger) Warning(p ...interface{}) {
+	s.l.Log(s.ctx, slog.LevelWarn, sprint(p))
+}
+
+func (s *slogLogger) Debug(p ...interface{}) {
+	s.l.Log(s.ctx, slog.LevelDebug, sprint(p))
+}
+
+// sprint formats p with spaces between all operands, fmt.Sprint adds them only between non-strings.
+func sprint(p []interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(p...), "\n")
+}
+
+func (s *slogLogger) Log(level LogLevel, event LogEvent) {
+	slogLevel := slog.LevelDebug
+	switch level {
+	case LevelWarning:
+		slogLevel = slog.LevelWarn
+	case LevelError:
+		slogLevel = slog.LevelError
+	}
+
+	if !s.l.Enabled(s.ctx, slogLevel) {
+		return
+	}
+
+	var attrs []slog.Attr
+	if event.Query != "" {
+		attrs = append(attrs, slog.String("query", event.Query))
+	}
+	// events of failover, circuit breaker, etc. aren't calls and have no call attributes
+	if event.Operation != "" {
+		attrs = append(attrs,
+			slog.String("operation", string(event.Operation)),
+			slog.Duration("duration", event.Duration),
+			slog.Duration("queue_time", event.QueueTime),
+			slog.Int64("rows", event.Rows),
+			slog.Int("attempts", event.Attempts),
+			slog.String("role", string(event.Role)),
+		)
+	}
+
+	if event.TransactionTime > 0 {
+		attrs = append(attrs, slog.Duration("transaction_time", event.TransactionTime))
+	}
+	if event.TransactionID != 0 {
+		attrs = append(attrs, slog.Uint64("transaction_id", event.TransactionID))
+	}
+	if event.Args != nil {
+		attrs = append(attrs, slog.Any("args", event.Args))
+	}
+	if event.Caller != "" {
+		attrs = append(attrs, slog.String("caller", event.Caller))
+	}
+	if event.Err != nil {
+		attrs = append(attrs, slog.String("error", event.Err.Error()))
+	}
+
+	s.l.LogAttrs(s.ctx, slogLevel, event.Message, attrs...)
+}
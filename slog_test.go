@@ -0,0 +1,42 @@
+//go:build go1.21
+// +build go1.21
+
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogLoggerSeparatesArgs(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSlogLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	l.Warning("kill query", 5, "SELECT 1")
+
+	if !strings.Contains(buf.String(), `msg="kill query 5 SELECT 1"`) {
+		t.Errorf("expected args separated by spaces, got %q", buf.String())
+	}
+}
+
+func TestSlogLoggerLogsEvents(t *testing.T) {
+	var buf bytes.Buffer
+
+	cfg := NewDefaultConfig()
+	cfg.Logger = NewSlogLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	logEvent(context.Background(), cfg, LevelWarning, LogEvent{Message: "circuit breaker: closed -> open"})
+	if out := buf.String(); !strings.Contains(out, `msg="circuit breaker: closed -> open"`) || strings.Contains(out, "operation=") {
+		t.Errorf("expected a message without call attributes, got %q", out)
+	}
+
+	buf.Reset()
+	logEvent(context.Background(), cfg, LevelError, LogEvent{Message: "kill query", Query: "KILL QUERY 5", Err: errors.New("bad connection")})
+	if out := buf.String(); !strings.Contains(out, `query="KILL QUERY 5"`) || !strings.Contains(out, `error="bad connection"`) {
+		t.Errorf("expected query and error attributes, got %q", out)
+	}
+}
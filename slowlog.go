@@ -0,0 +1,105 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// packagePrefix is used to skip frames of this package when looking for the caller
+var packagePrefix = func() string {
+	name := runtime.FuncForPC(reflect.ValueOf(newSlowLog).Pointer()).Name()
+	return name[:strings.LastIndex(name, ".")+1]
+}()
+
+// slowLog logs queries slower than the threshold with the Logger and writes them to a file in MySQL slow log format.
+type slowLog struct {
+	mu         sync.Mutex
+	threshold  time.Duration
+	sampleRate float64
+	cfg        *Config
+	w          io.Writer
+}
+
+func newSlowLog() *slowLog {
+	return &slowLog{}
+}
+
+func (l *slowLog) configure(cfg *Config) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.threshold = cfg.SlowQueryThreshold
+	l.sampleRate = cfg.SlowQuerySampleRate
+	l.cfg = cfg
+	l.w = cfg.SlowLogWriter
+}
+
+func (l *slowLog) observe(ctx context.Context, sample *QueryStats) {
+	l.mu.Lock()
+	threshold, sampleRate, cfg := l.threshold, l.sampleRate, l.cfg
+	l.mu.Unlock()
+
+	if threshold <= 0 || sample.ExecutionTime < threshold {
+		return
+	}
+
+	// a zero rate, e.g. of a config built without NewDefaultConfig, means every slow query is logged
+	if sampleRate > 0 && sampleRate < 1 && rand.Float64() >= sampleRate {
+		return
+	}
+
+	caller := callerLocation()
+
+	event := newLogEvent(sample)
+	event.Message = "slow query"
+	event.Args = redactArgs(cfg.LogArgs, sample.statement, sample.args)
+	event.Caller = caller
+	logEvent(ctx, cfg, LevelWarning, event)
+	sample.slowLogged = true
+
+	l.write(sample, caller)
+}
+
+// write writes the query in MySQL slow log format.
+func (l *slowLog) write(sample *QueryStats, caller string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.w == nil {
+		return
+	}
+
+	now := time.Now().UTC()
+	query := strings.TrimRight(strings.TrimSpace(sample.Query), ";")
+
+	fmt.Fprintf(l.w, "# Time: %s\n", now.Format("2006-01-02T15:04:05.000000Z"))
+	fmt.Fprintf(l.w, "# Query_time: %.6f  Lock_time: 0.000000  Rows_sent: %d  Rows_examined: 0  Rows_affected: %d\n",
+		sample.ExecutionTime.Seconds(), sample.RowsReturned, sample.RowsAffected)
+	fmt.Fprintf(l.w, "# Queue_time: %.6f  Caller: %s\n", sample.QueueTime.Seconds(), caller)
+	fmt.Fprintf(l.w, "SET timestamp=%d;\n%s;\n", now.Unix(), query)
+}
+
+// callerLocation returns file:line of the first caller outside of this package.
+func callerLocation() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, packagePrefix) {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+
+		if !more {
+			return "unknown"
+		}
+	}
+}
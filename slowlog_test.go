@@ -0,0 +1,74 @@
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowLogSampleRate(t *testing.T) {
+	tests := []struct {
+		rate   float64
+		logged bool
+	}{
+		{1, true},
+		{0, true},
+		{-1, true},
+		{0.0000001, false},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+
+		cfg := NewDefaultConfig()
+		cfg.SlowQueryThreshold = time.Millisecond
+		cfg.SlowQuerySampleRate = test.rate
+		cfg.SlowLogWriter = &buf
+
+		l := newSlowLog()
+		l.configure(cfg)
+		l.observe(context.Background(), &QueryStats{Query: "SELECT SLEEP(1)", ExecutionTime: time.Second})
+
+		if logged := strings.Contains(buf.String(), "SELECT SLEEP(1);"); logged != test.logged {
+			t.Errorf("rate %v: expected logged %v, got %q", test.rate, test.logged, buf.String())
+		}
+	}
+}
+
+func TestSlowLogThreshold(t *testing.T) {
+	var buf bytes.Buffer
+
+	cfg := NewDefaultConfig()
+	cfg.SlowQueryThreshold = time.Second
+	cfg.SlowLogWriter = &buf
+
+	l := newSlowLog()
+	l.configure(cfg)
+	l.observe(context.Background(), &QueryStats{Query: "SELECT 1", ExecutionTime: time.Millisecond})
+
+	if buf.Len() != 0 {
+		t.Errorf("expected a fast query not to be logged, got %q", buf.String())
+	}
+}
+
+func TestSlowQueryIsLoggedOnce(t *testing.T) {
+	l := &recordingLogger{}
+	c := newFakeClient(t, &fakeDriver{delay: time.Millisecond * 20}, func(cfg *Config) {
+		cfg.Logger = l
+		cfg.SlowQueryThreshold = time.Millisecond
+		cfg.LogLatencyThreshold = time.Millisecond
+	})
+
+	if _, err := c.Exec(context.Background(), "UPDATE `foo` SET `bar` = 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.levels) != 1 || l.levels[0] != LevelWarning {
+		t.Errorf("expected a single warning, got %v", l.levels)
+	}
+}
@@ -0,0 +1,123 @@
+package mysql
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const routeKey contextKey = "route"
+
+// commentCacheSize limits the number of cached commented statements, the cache is cleared when it's full
+const commentCacheSize = 10000
+
+// WithRoute returns a context with the route, like an HTTP endpoint or an RPC method, added to sql comments
+// when Config.SQLComment is enabled.
+//
+//  res, err := client.Query(mysql.WithRoute(ctx, "/v1/users"), "SELECT * FROM `users`;")
+func WithRoute(ctx context.Context, route string) context.Context {
+	return context.WithValue(ctx, routeKey, route)
+}
+
+func routeFromCtx(ctx context.Context) string {
+	if r, ok := ctx.Value(routeKey).(string); ok {
+		return r
+	}
+
+	return ""
+}
+
+// traceParenter is implemented by tracers able to return W3C traceparent of a span found in the context.
+type traceParenter interface {
+	TraceParent(ctx context.Context) string
+}
+
+type commentKey struct {
+	query       string
+	application string
+	route       string
+	tenant      string
+}
+
+// commenter appends sqlcommenter comments to statements, so the statements can be attributed to services
+// and requests in the processlist and server logs.
+//
+// Comments are cached per statement, route and tenant, so the same statement called for the same route gets
+// the same text. The traceparent is unique per call, it's added only with Config.SQLCommentTraceParent.
+type commenter struct {
+	mu    sync.Mutex
+	cache map[commentKey]comment
+}
+
+// comment is a statement without a trailing semicolon and sqlcommenter tags in alphabetical order.
+type comment struct {
+	query string
+	tags  string
+}
+
+func newCommenter() *commenter {
+	return &commenter{cache: make(map[commentKey]comment)}
+}
+
+// comment returns the statement with a comment appended if Config.SQLComment is enabled.
+func (cm *commenter) comment(ctx context.Context, cfg *Config, query string) string {
+	if !cfg.SQLComment {
+		return query
+	}
+
+	key := commentKey{query, cfg.ServiceName, routeFromCtx(ctx), tenantFromCtx(ctx)}
+
+	cm.mu.Lock()
+	c, found := cm.cache[key]
+	if !found {
+		if len(cm.cache) >= commentCacheSize {
+			cm.cache = make(map[commentKey]comment)
+		}
+		c = newComment(key)
+		cm.cache[key] = c
+	}
+	cm.mu.Unlock()
+
+	tags := c.tags
+	if tp, ok := cfg.Tracer.(traceParenter); ok && cfg.SQLCommentTraceParent {
+		if traceParent := tp.TraceParent(ctx); traceParent != "" {
+			if tags != "" {
+				tags += ","
+			}
+			tags += commentPair("traceparent", traceParent)
+		}
+	}
+
+	if tags == "" {
+		return query
+	}
+
+	return c.query + " /*" + tags + "*/"
+}
+
+func newComment(key commentKey) comment {
+	var tags []string
+	if key.application != "" {
+		tags = append(tags, commentPair("application", key.application))
+	}
+	if key.route != "" {
+		tags = append(tags, commentPair("route", key.route))
+	}
+	if key.tenant != "" {
+		tags = append(tags, commentPair("tenant", key.tenant))
+	}
+
+	query := strings.TrimRightFunc(key.query, func(r rune) bool {
+		return r == ';' || unicode.IsSpace(r)
+	})
+
+	return comment{query, strings.Join(tags, ",")}
+}
+
+// commentPair formats a sqlcommenter key value pair, both are url encoded and the value is quoted.
+// Encoding escapes quotes and comment delimiters too, so values can't end the comment.
+func commentPair(key, value string) string {
+	return url.PathEscape(key) + "='" + url.PathEscape(value) + "'"
+}
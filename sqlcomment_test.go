@@ -0,0 +1,98 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+)
+
+type traceParentTracer struct {
+	traceParent string
+}
+
+func (t *traceParentTracer) StartSpan(ctx context.Context, operation Operation, query string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+func (t *traceParentTracer) TraceParent(ctx context.Context) string {
+	return t.traceParent
+}
+
+func TestComment(t *testing.T) {
+	tests := []struct {
+		name      string
+		service   string
+		route     string
+		tenant    string
+		parent    string
+		query     string
+		commented string
+	}{
+		{"disabled tags", "", "", "", "", "SELECT 1;", "SELECT 1;"},
+		{"all tags", "users", "/v1/users", "acme", "", "SELECT 1", "SELECT 1 /*application='users',route='%2Fv1%2Fusers',tenant='acme'*/"},
+		{"trailing semicolon", "users", "", "", "", "SELECT 1; \n", "SELECT 1 /*application='users'*/"},
+		{"escaped value", "users", "/x'*/ DROP TABLE users; /*", "", "", "SELECT 1", "SELECT 1 /*application='users',route='%2Fx%27%2A%2F%20DROP%20TABLE%20users%3B%20%2F%2A'*/"},
+		{"traceparent", "users", "", "", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", "SELECT 1",
+			"SELECT 1 /*application='users',traceparent='00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01'*/"},
+		{"traceparent only", "", "", "", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", "SELECT 1;",
+			"SELECT 1 /*traceparent='00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01'*/"},
+	}
+
+	for _, test := range tests {
+		cfg := NewDefaultConfig()
+		cfg.SQLComment = true
+		cfg.ServiceName = test.service
+		cfg.Tracer = &traceParentTracer{test.parent}
+		cfg.SQLCommentTraceParent = true
+
+		ctx := WithTenant(WithRoute(context.Background(), test.route), test.tenant)
+		if commented := newCommenter().comment(ctx, cfg, test.query); commented != test.commented {
+			t.Errorf("%s: expected %q, got %q", test.name, test.commented, commented)
+		}
+	}
+}
+
+func TestCommentIsDisabled(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.ServiceName = "users"
+
+	if commented := newCommenter().comment(context.Background(), cfg, "SELECT 1;"); commented != "SELECT 1;" {
+		t.Errorf("expected the query without a comment, got %q", commented)
+	}
+}
+
+func TestCommentCache(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.SQLComment = true
+	cfg.ServiceName = "users"
+
+	tracer := &traceParentTracer{"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}
+	cfg.Tracer = tracer
+	cfg.SQLCommentTraceParent = true
+
+	cm := newCommenter()
+	ctx := WithRoute(context.Background(), "/v1/users")
+
+	cm.comment(ctx, cfg, "SELECT 1")
+	cm.comment(ctx, cfg, "SELECT 1")
+	cm.comment(WithRoute(context.Background(), "/v1/teams"), cfg, "SELECT 1")
+	cm.comment(WithTenant(ctx, "acme"), cfg, "SELECT 1")
+
+	if len(cm.cache) != 3 {
+		t.Errorf("expected a comment cached per statement, route and tenant, got %d", len(cm.cache))
+	}
+
+	// the traceparent differs per call, so it isn't cached
+	tracer.traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	if commented := cm.comment(ctx, cfg, "SELECT 1"); commented != "SELECT 1 /*application='users',route='%2Fv1%2Fusers',traceparent='00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01'*/" {
+		t.Errorf("expected the current traceparent, got %q", commented)
+	}
+
+	for i := len(cm.cache); i < commentCacheSize; i++ {
+		cm.cache[commentKey{query: string(rune(i))}] = comment{}
+	}
+	cm.comment(ctx, cfg, "SELECT 2")
+
+	if len(cm.cache) != 1 {
+		t.Errorf("expected the full cache to be cleared, got %d comments", len(cm.cache))
+	}
+}
@@ -1,8 +1,37 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	mysql "github.com/go-sql-driver/mysql"
+)
+
+// Operation is a type of a call measured in stats.
+//
+type Operation string
+
+const (
+	OperationQuery    Operation = "query" // Query, MultiQuery, Call and MultiCall
+	OperationExec     Operation = "exec"
+	OperationBegin    Operation = "begin"
+	OperationCommit   Operation = "commit"
+	OperationRollback Operation = "rollback"
+)
+
+var operations = []Operation{OperationQuery, OperationExec, OperationBegin, OperationCommit, OperationRollback}
+
+// Role tells if a statement was executed by a client or by its replica set with SetReplica.
+//
+type Role string
+
+const (
+	RolePrimary Role = "primary"
+	RoleReplica Role = "replica"
 )
 
 // internal stats struct
@@ -10,23 +39,107 @@ type stats struct {
 	inProgressQueries   *int64
 	totalSuccessQueries *int64
 	totalFailedQueries  *int64
-	sampleChan          chan *QueryStats
+	limiter             *limiter
+	latency             map[Operation]*latency
+	digests             *digests
+	slowLog             *slowLog
+
+	mu     sync.Mutex
+	errors map[uint16]int64 // failed queries by mysql error number
+
+	subMu          sync.RWMutex
+	subscriptions  []*Subscription
+	sampleSub      *Subscription // subscription behind SamplesChan
+	droppedSamples *int64
 }
 
-func (s *stats) sample(sample *QueryStats) {
-	select {
-	case s.sampleChan <- sample:
-	default:
+type latency struct {
+	executionTime   *histogram
+	queueTime       *histogram
+	transactionTime *histogram
+}
+
+// sample records a finished call. Calls rejected before reaching the database, with no attempts,
+// are recorded too, but they aren't observed by the limiter.
+func (s *stats) sample(ctx context.Context, sample *QueryStats) {
+	if sample.Attempts > 0 {
+		s.limiter.observe(sample.ExecutionTime)
+	}
+
+	if l, found := s.latency[sample.Operation]; found {
+		l.executionTime.observe(sample.ExecutionTime.Seconds())
+		l.queueTime.observe(sample.QueueTime.Seconds())
+
+		if sample.Operation == OperationCommit || sample.Operation == OperationRollback {
+			l.transactionTime.observe(sample.TransactionTime.Seconds())
+		}
+	}
+
+	s.digests.observe(sample.Query, sample.ExecutionTime, sample.RowsReturned+sample.RowsAffected, sample.Err != nil)
+	s.slowLog.observe(ctx, sample)
+	s.publish(sample)
+}
+
+// failed counts a failed query.
+func (s *stats) failed(err error) {
+	atomic.AddInt64(s.totalFailedQueries, 1)
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		s.mu.Lock()
+		s.errors[mysqlErr.Number]++
+		s.mu.Unlock()
 	}
 }
 
+func (s *stats) errorStats() map[uint16]int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	es := make(map[uint16]int64, len(s.errors))
+	for number, count := range s.errors {
+		es[number] = count
+	}
+
+	return es
+}
+
 func newStats() *stats {
-	return &stats{
+	s := &stats{
 		inProgressQueries:   new(int64),
 		totalSuccessQueries: new(int64),
 		totalFailedQueries:  new(int64),
-		sampleChan:          make(chan *QueryStats, 100),
+		limiter:             newLimiter(),
+		latency:             make(map[Operation]*latency, len(operations)),
+		errors:              make(map[uint16]int64),
+		digests:             newDigests(),
+		slowLog:             newSlowLog(),
+		droppedSamples:      new(int64),
+	}
+	s.sampleSub = s.subscribe(100, OverflowDrop)
+
+	for _, op := range operations {
+		s.latency[op] = &latency{
+			executionTime:   newHistogram(durationBuckets),
+			queueTime:       newHistogram(durationBuckets),
+			transactionTime: newHistogram(durationBuckets),
+		}
+	}
+
+	return s
+}
+
+func (s *stats) latencyStats() map[Operation]LatencyStats {
+	ls := make(map[Operation]LatencyStats, len(s.latency))
+	for op, l := range s.latency {
+		ls[op] = LatencyStats{
+			ExecutionTime:   l.executionTime.snapshot(),
+			QueueTime:       l.queueTime.snapshot(),
+			TransactionTime: l.transactionTime.snapshot(),
+		}
 	}
+
+	return ls
 }
 
 type Stats struct {
@@ -34,11 +147,72 @@ type Stats struct {
 	InProgressQueries   int64
 	TotalSuccessQueries int64
 	TotalFailedQueries  int64
+	TotalKilledQueries  int64 // queries killed on the server after context cancellation
+	Priorities          map[Priority]PriorityStats
+	Tenants             map[string]TenantStats
+	QueueWait           Histogram // time spent waiting for connection in seconds
+	QueueLength         Histogram // number of calls already waiting when a call asks for connection
+	ConcurrencyLimit    int64     // current adaptive limit of in-flight calls, 0 if Config.AdaptiveLimit is disabled
+	CircuitState        CircuitState
+	CircuitTransitions  int64 // number of circuit breaker state changes
+	ActiveEndpoint      int   // index of the endpoint writes go to, see NewFailoverClient
+	Latency             map[Operation]LatencyStats
+	Errors              map[uint16]int64 // failed queries by mysql error number
+	DroppedSamples      int64            // samples not delivered to subscriptions, including SamplesChan
+}
+
+// LatencyStats holds latency histograms of an operation in seconds.
+//
+type LatencyStats struct {
+	ExecutionTime   Histogram
+	QueueTime       Histogram
+	TransactionTime Histogram // observed for commit and rollback only
+}
+
+// PriorityStats describes a priority lane of the relay queue.
+//
+type PriorityStats struct {
+	Queued         int64         // queries waiting for connection
+	Admitted       int64         // total queries admitted from the lane
+	TotalQueueTime time.Duration // total time spent in queue by admitted queries
+}
+
+// TenantStats describes queries of a tenant set with WithTenant. Tenants without queries for 10 minutes
+// are removed from Stats, their totals start from zero when they come back.
+//
+type TenantStats struct {
+	InProgressQueries    int64
+	TotalSuccessQueries  int64
+	TotalFailedQueries   int64 // failed queries, not including rejected ones
+	TotalRejectedQueries int64 // queries rejected because of Config.MaxTenantQueries
 }
 
 type QueryStats struct {
-	Query           string        // measured query
+	Query           string        // measured query, redacted according to Config.RedactLiterals and Config.RedactPatterns
+	Operation       Operation     // type of the call
 	ExecutionTime   time.Duration // time of query executoion including with db roudntrip
 	QueueTime       time.Duration // time spent in queue waiting for connection
 	TransactionTime time.Duration // total transaction time (returned for commit and rollback queries)
+	Priority        Priority      // priority lane the query waited in for connection
+	RowsReturned    int64         // rows returned by queries
+	RowsAffected    int64         // rows affected by execs
+	Err             error         // error returned to the caller, nil on success, a QueryError message is redacted like Query
+	ErrorNumber     uint16        // mysql error number of Err, 0 on success or if the error is not a mysql error
+	Args            int           // number of arguments passed with the query
+	Attempts        int           // number of executions, greater than 1 when retried on deadlock or after failover
+	Role            Role          // role of the client which executed the statement
+	TransactionID   uint64        // id of the transaction the statement belongs to, 0 outside of transactions
+	StartedAt       time.Time     // time of the call, before waiting in queue
+	Merged          int64         // number of samples merged into this one by OverflowAggregate, durations and rows are sums
+
+	statement  string // query as executed, before redaction
+	args       []interface{}
+	slowLogged bool // logged at warning level by the slow log
+}
+
+// finish sets the error returned to the caller and returns the sample.
+func (s *QueryStats) finish(err error) *QueryStats {
+	s.Err = err
+	s.ErrorNumber = errorNumber(err)
+	return s
 }
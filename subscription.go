@@ -0,0 +1,232 @@
+package mysql
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// aggregateFlushInterval is how often pending aggregates are delivered when there are no new samples
+const aggregateFlushInterval = time.Millisecond * 100
+
+// OverflowPolicy decides what happens with a sample when a subscription buffer is full.
+//
+type OverflowPolicy int
+
+const (
+	OverflowDrop      OverflowPolicy = iota // the sample is dropped and counted in Stats.DroppedSamples
+	OverflowBlock                           // the query waits until the subscriber makes room, use with care
+	OverflowAggregate                       // the sample is merged with other samples of the same query and operation
+)
+
+// Subscription delivers samples of all queries made by a client.
+//
+type Subscription struct {
+	c       chan *QueryStats
+	policy  OverflowPolicy
+	limit   int // limit of pending aggregates
+	done    chan struct{}
+	flusher chan struct{} // closed when the goroutine flushing aggregates exits, nil if there is none
+	once    sync.Once
+	s       *stats
+
+	mu      sync.Mutex
+	pending map[aggregateKey]*QueryStats
+	order   []aggregateKey
+}
+
+type aggregateKey struct {
+	query     string
+	operation Operation
+}
+
+// Subscribe returns a subscription delivering samples through a channel with bufferSize buffer.
+// Each subscription receives all samples independently of others, it must be closed when no longer used.
+//
+//  sub := client.Subscribe(1000, mysql.OverflowAggregate)
+//  defer sub.Close()
+//
+//  for sample := range sub.C() {
+//    // handle sample
+//  }
+func (c *Client) Subscribe(bufferSize int, policy OverflowPolicy) *Subscription {
+	return c.s.subscribe(bufferSize, policy)
+}
+
+// C returns the channel of samples, it's closed when the subscription is closed.
+//
+func (sub *Subscription) C() <-chan *QueryStats {
+	return sub.c
+}
+
+// Close stops the delivery and closes the channel. Pending aggregates are discarded and counted in Stats.DroppedSamples.
+//
+func (sub *Subscription) Close() {
+	sub.once.Do(func() {
+		close(sub.done)
+		sub.s.unsubscribe(sub)
+		if sub.flusher != nil {
+			<-sub.flusher
+		}
+
+		sub.mu.Lock()
+		for _, agg := range sub.pending {
+			atomic.AddInt64(sub.s.droppedSamples, agg.Merged+1)
+		}
+		sub.pending, sub.order = nil, nil
+		sub.mu.Unlock()
+
+		close(sub.c)
+	})
+}
+
+func (s *stats) subscribe(bufferSize int, policy OverflowPolicy) *Subscription {
+	if bufferSize < 0 {
+		bufferSize = 0
+	}
+
+	sub := &Subscription{
+		c:       make(chan *QueryStats, bufferSize),
+		policy:  policy,
+		limit:   bufferSize,
+		done:    make(chan struct{}),
+		s:       s,
+		pending: make(map[aggregateKey]*QueryStats),
+	}
+
+	if policy == OverflowAggregate {
+		sub.flusher = make(chan struct{})
+		go sub.flushEvery(aggregateFlushInterval)
+	}
+
+	s.subMu.Lock()
+	s.subscriptions = append(s.subscriptions, sub)
+	s.subMu.Unlock()
+
+	return sub
+}
+
+// flushEvery delivers pending aggregates when the consumer makes room, even if no new samples are published.
+func (sub *Subscription) flushEvery(interval time.Duration) {
+	defer close(sub.flusher)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			sub.mu.Lock()
+			sub.flush()
+			sub.mu.Unlock()
+		case <-sub.done:
+			return
+		}
+	}
+}
+
+// unsubscribe waits until samples being delivered to the subscription are delivered.
+func (s *stats) unsubscribe(sub *Subscription) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	for i, registered := range s.subscriptions {
+		if registered == sub {
+			s.subscriptions = append(s.subscriptions[:i:i], s.subscriptions[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *stats) publish(sample *QueryStats) {
+	s.subMu.RLock()
+	defer s.subMu.RUnlock()
+
+	for _, sub := range s.subscriptions {
+		if !sub.deliver(sample) {
+			atomic.AddInt64(s.droppedSamples, 1)
+		}
+	}
+}
+
+// deliver returns false if the sample was dropped.
+func (sub *Subscription) deliver(sample *QueryStats) bool {
+	switch sub.policy {
+	case OverflowBlock:
+		select {
+		case sub.c <- sample:
+		case <-sub.done:
+			return false
+		}
+
+		return true
+
+	case OverflowAggregate:
+		sub.mu.Lock()
+		defer sub.mu.Unlock()
+
+		sub.flush()
+		if len(sub.order) == 0 {
+			select {
+			case sub.c <- sample:
+				return true
+			default:
+			}
+		}
+
+		return sub.aggregate(sample)
+	}
+
+	select {
+	case sub.c <- sample:
+		return true
+	default:
+		return false
+	}
+}
+
+// flush delivers pending aggregates in order while there is room in the buffer, sub.mu must be held.
+func (sub *Subscription) flush() {
+	for len(sub.order) > 0 {
+		key := sub.order[0]
+
+		select {
+		case sub.c <- sub.pending[key]:
+			delete(sub.pending, key)
+			sub.order = sub.order[1:]
+		default:
+			return
+		}
+	}
+}
+
+// aggregate merges the sample into a pending aggregate, it returns false if there are too many aggregates.
+// sub.mu must be held.
+func (sub *Subscription) aggregate(sample *QueryStats) bool {
+	key := aggregateKey{sample.Query, sample.Operation}
+
+	agg, found := sub.pending[key]
+	if !found {
+		if len(sub.order) >= sub.limit {
+			return false
+		}
+
+		copied := *sample
+		sub.pending[key] = &copied
+		sub.order = append(sub.order, key)
+		return true
+	}
+
+	agg.Merged++
+	agg.ExecutionTime += sample.ExecutionTime
+	agg.QueueTime += sample.QueueTime
+	agg.TransactionTime += sample.TransactionTime
+	agg.RowsReturned += sample.RowsReturned
+	agg.RowsAffected += sample.RowsAffected
+
+	if sample.Err != nil {
+		agg.Err = sample.Err
+	}
+
+	return true
+}
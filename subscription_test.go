@@ -0,0 +1,130 @@
+package mysql
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func receive(t *testing.T, sub *Subscription) *QueryStats {
+	select {
+	case sample := <-sub.C():
+		return sample
+	case <-time.After(aggregateFlushInterval * 5):
+		t.Fatal("expected a sample")
+		return nil
+	}
+}
+
+func TestSubscriptionDropsSamples(t *testing.T) {
+	s := newStats()
+	sub := s.subscribe(1, OverflowDrop)
+	defer sub.Close()
+
+	for i := 0; i < 3; i++ {
+		s.publish(&QueryStats{Query: "SELECT 1"})
+	}
+
+	if sample := receive(t, sub); sample.Query != "SELECT 1" {
+		t.Errorf("unexpected sample %+v", sample)
+	}
+	if dropped := atomic.LoadInt64(s.droppedSamples); dropped != 2 {
+		t.Errorf("expected 2 dropped samples, got %d", dropped)
+	}
+}
+
+func TestSubscriptionFlushesAggregates(t *testing.T) {
+	s := newStats()
+	sub := s.subscribe(2, OverflowAggregate)
+	defer sub.Close()
+
+	for i := 0; i < 4; i++ {
+		s.publish(&QueryStats{Query: "SELECT 1", Operation: OperationQuery, ExecutionTime: time.Millisecond, RowsReturned: int64(i)})
+	}
+	s.publish(&QueryStats{Query: "SELECT 2", Operation: OperationQuery})
+
+	tests := []struct {
+		query         string
+		merged        int64
+		executionTime time.Duration
+		rows          int64
+	}{
+		{"SELECT 1", 0, time.Millisecond, 0},
+		{"SELECT 1", 0, time.Millisecond, 1},
+		{"SELECT 1", 1, time.Millisecond * 2, 5},
+		{"SELECT 2", 0, 0, 0},
+	}
+
+	// pending aggregates are delivered without new samples being published
+	for _, test := range tests {
+		sample := receive(t, sub)
+		if sample.Query != test.query || sample.Merged != test.merged || sample.ExecutionTime != test.executionTime || sample.RowsReturned != test.rows {
+			t.Errorf("expected %+v, got %+v", test, sample)
+		}
+	}
+
+	if dropped := atomic.LoadInt64(s.droppedSamples); dropped != 0 {
+		t.Errorf("expected no dropped samples, got %d", dropped)
+	}
+}
+
+func TestSubscriptionLimitsAggregates(t *testing.T) {
+	s := newStats()
+	sub := s.subscribe(1, OverflowAggregate)
+	defer sub.Close()
+
+	s.publish(&QueryStats{Query: "SELECT 1"})
+	s.publish(&QueryStats{Query: "SELECT 2"})
+	s.publish(&QueryStats{Query: "SELECT 3"})
+
+	if dropped := atomic.LoadInt64(s.droppedSamples); dropped != 1 {
+		t.Errorf("expected 1 dropped sample, got %d", dropped)
+	}
+}
+
+func TestSubscriptionCloseCountsPendingAggregates(t *testing.T) {
+	s := newStats()
+	sub := s.subscribe(1, OverflowAggregate)
+
+	for i := 0; i < 3; i++ {
+		s.publish(&QueryStats{Query: "SELECT 1"})
+	}
+	sub.Close()
+
+	if dropped := atomic.LoadInt64(s.droppedSamples); dropped != 2 {
+		t.Errorf("expected 2 dropped samples, got %d", dropped)
+	}
+
+	// the buffered sample is still delivered before the channel is closed
+	if _, ok := <-sub.C(); !ok {
+		t.Error("expected the buffered sample")
+	}
+	if _, ok := <-sub.C(); ok {
+		t.Error("expected the channel to be closed")
+	}
+}
+
+func TestSubscriptionBlocks(t *testing.T) {
+	s := newStats()
+	sub := s.subscribe(0, OverflowBlock)
+
+	published := make(chan struct{})
+	go func() {
+		s.publish(&QueryStats{Query: "SELECT 1"})
+		close(published)
+	}()
+
+	if sample := receive(t, sub); sample.Query != "SELECT 1" {
+		t.Errorf("unexpected sample %+v", sample)
+	}
+	<-published
+
+	// closing releases blocked publishers, the sample is counted as dropped
+	go s.publish(&QueryStats{Query: "SELECT 2"})
+	time.Sleep(time.Millisecond * 10)
+	sub.Close()
+
+	waitFor(t, func() bool {
+		return atomic.LoadInt64(s.droppedSamples) == 1
+	})
+}
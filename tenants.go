@@ -0,0 +1,154 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const tenantKey contextKey = "tenant"
+
+const (
+	tenantIdleTimeout   = time.Minute * 10 // counters of tenants without queries for this long are removed
+	tenantSweepInterval = time.Minute
+)
+
+// WithTenant returns a context with queries accounted to the tenant. Tenants have separate in-flight limits
+// set with Config.MaxTenantQueries and are served in turns when queries wait for connection.
+//
+//  res, err := client.Query(mysql.WithTenant(ctx, licenceID), "SELECT * FROM `foo`;")
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenant)
+}
+
+func tenantFromCtx(ctx context.Context) string {
+	if t, ok := ctx.Value(tenantKey).(string); ok {
+		return t
+	}
+
+	return ""
+}
+
+// tenants tracks per tenant counters, queries without a tenant aren't tracked.
+// Counters of idle tenants are removed, so the number of tracked tenants is bounded by the active ones.
+type tenants struct {
+	mu        sync.Mutex
+	m         map[string]*tenantCounters
+	lastSweep time.Time
+}
+
+type tenantCounters struct {
+	inProgress int64
+	success    int64
+	failed     int64
+	rejected   int64
+	lastUsed   time.Time
+}
+
+func newTenants() *tenants {
+	return &tenants{m: make(map[string]*tenantCounters)}
+}
+
+// start marks a tenant query as in progress, end must be called even if the limit is reached.
+// If limit <= 0 then there is no limit.
+func (t *tenants) start(tenant string, limit int64) error {
+	if tenant == "" {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.counters(tenant)
+	c.inProgress++
+
+	if limit > 0 && c.inProgress > limit {
+		c.rejected++
+		return ErrTenantOverloaded
+	}
+
+	return nil
+}
+
+func (t *tenants) end(tenant string, err error) {
+	if tenant == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.counters(tenant)
+	c.inProgress--
+
+	// rejected queries are already counted by start
+	if !errors.Is(err, ErrTenantOverloaded) {
+		c.count(err)
+	}
+}
+
+// count counts a query which doesn't hold in progress slot, like queries in a transaction.
+func (t *tenants) count(tenant string, err error) {
+	if tenant == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.counters(tenant).count(err)
+}
+
+// counters returns counters of the tenant marked as used, t.mu must be held.
+func (t *tenants) counters(tenant string) *tenantCounters {
+	now := time.Now()
+	if now.Sub(t.lastSweep) >= tenantSweepInterval {
+		t.sweep(now)
+	}
+
+	c, found := t.m[tenant]
+	if !found {
+		c = &tenantCounters{}
+		t.m[tenant] = c
+	}
+	c.lastUsed = now
+
+	return c
+}
+
+// sweep removes counters of tenants idle for tenantIdleTimeout, t.mu must be held.
+func (t *tenants) sweep(now time.Time) {
+	t.lastSweep = now
+
+	for tenant, c := range t.m {
+		if c.inProgress <= 0 && now.Sub(c.lastUsed) >= tenantIdleTimeout {
+			delete(t.m, tenant)
+		}
+	}
+}
+
+func (c *tenantCounters) count(err error) {
+	if err == nil {
+		c.success++
+	} else {
+		c.failed++
+	}
+}
+
+func (t *tenants) stats() map[string]TenantStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := make(map[string]TenantStats, len(t.m))
+	for tenant, c := range t.m {
+		s[tenant] = TenantStats{
+			InProgressQueries:    c.inProgress,
+			TotalSuccessQueries:  c.success,
+			TotalFailedQueries:   c.failed,
+			TotalRejectedQueries: c.rejected,
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,101 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTenantsCounters(t *testing.T) {
+	tn := newTenants()
+
+	tests := []struct {
+		startErr error
+		endErr   error
+	}{
+		{nil, nil},
+		{nil, errors.New("failed")},
+		{ErrTenantOverloaded, ErrTenantOverloaded},
+	}
+
+	for _, test := range tests {
+		if err := tn.start("foo", 2); !errors.Is(err, test.startErr) {
+			t.Fatalf("expected %v, got %v", test.startErr, err)
+		}
+	}
+	for _, test := range tests {
+		tn.end("foo", test.endErr)
+	}
+	tn.count("foo", nil)
+
+	expected := TenantStats{InProgressQueries: 0, TotalSuccessQueries: 2, TotalFailedQueries: 1, TotalRejectedQueries: 1}
+	if s := tn.stats()["foo"]; s != expected {
+		t.Errorf("expected %+v, got %+v", expected, s)
+	}
+
+	tn.start("", 1)
+	tn.end("", nil)
+	if _, found := tn.stats()[""]; found {
+		t.Error("expected queries without a tenant not to be tracked")
+	}
+}
+
+func TestTenantsSweep(t *testing.T) {
+	tn := newTenants()
+
+	tn.start("idle", 0)
+	tn.end("idle", nil)
+	tn.start("busy", 0)
+	tn.start("recent", 0)
+	tn.end("recent", nil)
+
+	now := time.Now()
+	tn.m["idle"].lastUsed = now.Add(-tenantIdleTimeout)
+	tn.m["busy"].lastUsed = now.Add(-tenantIdleTimeout)
+	tn.sweep(now)
+
+	s := tn.stats()
+	if _, found := s["idle"]; found {
+		t.Error("expected the idle tenant to be removed")
+	}
+	for _, tenant := range []string{"busy", "recent"} {
+		if _, found := s[tenant]; !found {
+			t.Errorf("expected %s tenant to be kept", tenant)
+		}
+	}
+}
+
+func TestTenantLimit(t *testing.T) {
+	d := &fakeDriver{delay: time.Millisecond * 100}
+	c := newFakeClient(t, d, func(cfg *Config) {
+		cfg.MaxTenantQueries = 1
+	})
+
+	ctx := WithTenant(context.Background(), "foo")
+
+	done := make(chan error)
+	go func() {
+		_, err := c.Exec(ctx, "UPDATE `foo` SET `bar` = 1")
+		done <- err
+	}()
+
+	waitFor(t, func() bool {
+		return c.Stats().Tenants["foo"].InProgressQueries == 1
+	})
+
+	if _, err := c.Exec(ctx, "UPDATE `foo` SET `bar` = 2"); !errors.Is(err, ErrTenantOverloaded) {
+		t.Errorf("expected ErrTenantOverloaded, got %v", err)
+	}
+	if _, err := c.Exec(WithTenant(context.Background(), "bar"), "UPDATE `foo` SET `bar` = 3"); err != nil {
+		t.Errorf("expected other tenants not to be limited, got %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	expected := TenantStats{TotalSuccessQueries: 1, TotalRejectedQueries: 1}
+	if s := c.Stats().Tenants["foo"]; s != expected {
+		t.Errorf("expected %+v, got %+v", expected, s)
+	}
+}
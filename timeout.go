@@ -0,0 +1,120 @@
+package mysql
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type contextKey string
+
+const queryOptionsKey contextKey = "queryOptions"
+
+// QueryOption overrides the client config for calls made with the context.
+//
+//  ctx = mysql.WithQueryOptions(ctx, mysql.Timeout(time.Second), mysql.MaxExecutionTime(time.Second))
+//  res, err := client.Query(ctx, "SELECT * FROM `foo`;")
+type QueryOption func(*queryOptions)
+
+type queryOptions struct {
+	timeout          *time.Duration
+	maxExecutionTime *time.Duration
+}
+
+// Timeout overrides Config.Timeout, if d <= 0 then timeout isn't set.
+//
+func Timeout(d time.Duration) QueryOption {
+	return func(o *queryOptions) {
+		o.timeout = &d
+	}
+}
+
+// MaxExecutionTime sets a MAX_EXECUTION_TIME hint for SELECT queries, if d <= 0 then the hint isn't set.
+// It overrides Config.ServerSideTimeout.
+//
+func MaxExecutionTime(d time.Duration) QueryOption {
+	return func(o *queryOptions) {
+		o.maxExecutionTime = &d
+	}
+}
+
+// WithQueryOptions returns a context with options applied to all calls made with it.
+// Options already found in the context are kept unless overridden.
+//
+func WithQueryOptions(ctx context.Context, opts ...QueryOption) context.Context {
+	o := queryOptionsFromCtx(ctx)
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return context.WithValue(ctx, queryOptionsKey, o)
+}
+
+func queryOptionsFromCtx(ctx context.Context) queryOptions {
+	if o, ok := ctx.Value(queryOptionsKey).(queryOptions); ok {
+		return o
+	}
+
+	return queryOptions{}
+}
+
+// withTimeout applies a query timeout to the context. An earlier deadline set by the caller is respected.
+func withTimeout(ctx context.Context, cfg *Config) (context.Context, context.CancelFunc) {
+	timeout := cfg.Timeout
+	if o := queryOptionsFromCtx(ctx); o.timeout != nil {
+		timeout = *o.timeout
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if timeout <= 0 || time.Until(deadline) <= timeout {
+			return ctx, func() {}
+		}
+	}
+
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// maxExecutionTime returns query with MAX_EXECUTION_TIME hint if it's enabled for the context.
+// With Config.ServerSideTimeout the hint is the time left until the context deadline, so it has to be called
+// right before the query is sent.
+func maxExecutionTime(ctx context.Context, cfg *Config, query string) string {
+	var timeout time.Duration
+	if o := queryOptionsFromCtx(ctx); o.maxExecutionTime != nil {
+		timeout = *o.maxExecutionTime
+	} else if !cfg.ServerSideTimeout {
+		return query
+	} else if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	if timeout <= 0 {
+		return query
+	}
+
+	return withMaxExecutionTimeHint(query, timeout)
+}
+
+func withMaxExecutionTimeHint(query string, timeout time.Duration) string {
+	trimmed := strings.TrimLeftFunc(query, unicode.IsSpace)
+	if len(trimmed) < len("SELECT") || !strings.EqualFold(trimmed[:len("SELECT")], "SELECT") {
+		return query
+	}
+
+	if strings.Contains(strings.ToUpper(query), "MAX_EXECUTION_TIME") {
+		return query
+	}
+
+	ms := timeout.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+
+	offset := len(query) - len(trimmed) + len("SELECT")
+	return query[:offset] + " /*+ MAX_EXECUTION_TIME(" + strconv.FormatInt(ms, 10) + ") */" + query[offset:]
+}
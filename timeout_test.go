@@ -0,0 +1,168 @@
+package mysql
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeout  time.Duration
+		opts     []QueryOption
+		deadline time.Duration
+		want     time.Duration
+	}{
+		{"config timeout", time.Second, nil, 0, time.Second},
+		{"no timeout", 0, nil, 0, 0},
+		{"earlier caller deadline wins", time.Second, nil, 100 * time.Millisecond, 100 * time.Millisecond},
+		{"later caller deadline is shortened", 100 * time.Millisecond, nil, time.Second, 100 * time.Millisecond},
+		{"caller deadline without timeout", 0, nil, time.Second, time.Second},
+		{"option overrides config", time.Second, []QueryOption{Timeout(100 * time.Millisecond)}, 0, 100 * time.Millisecond},
+		{"zero option disables timeout", time.Second, []QueryOption{Timeout(0)}, 0, 0},
+		{"zero option keeps caller deadline", time.Second, []QueryOption{Timeout(0)}, 2 * time.Second, 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewDefaultConfig()
+			cfg.Timeout = tt.timeout
+
+			ctx := WithQueryOptions(context.Background(), tt.opts...)
+			if tt.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.deadline)
+				defer cancel()
+			}
+
+			ctx, cancel := withTimeout(ctx, cfg)
+			defer cancel()
+
+			deadline, ok := ctx.Deadline()
+			if tt.want == 0 {
+				if ok {
+					t.Fatalf("unexpected deadline in %v", time.Until(deadline))
+				}
+				return
+			}
+
+			if !ok {
+				t.Fatalf("no deadline, want %v", tt.want)
+			}
+			if left := time.Until(deadline); left > tt.want || left < tt.want-50*time.Millisecond {
+				t.Errorf("deadline in %v, want %v", left, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithMaxExecutionTimeHint(t *testing.T) {
+	tests := []struct {
+		query   string
+		timeout time.Duration
+		want    string
+	}{
+		{"SELECT * FROM foo", time.Second, "SELECT /*+ MAX_EXECUTION_TIME(1000) */ * FROM foo"},
+		{"select id from foo", 250 * time.Millisecond, "select /*+ MAX_EXECUTION_TIME(250) */ id from foo"},
+		{"  \n\tSELECT 1", time.Second, "  \n\tSELECT /*+ MAX_EXECUTION_TIME(1000) */ 1"},
+		{"SELECT 1", time.Microsecond, "SELECT /*+ MAX_EXECUTION_TIME(1) */ 1"},
+		{"SELECT /*+ MAX_EXECUTION_TIME(50) */ 1", time.Second, "SELECT /*+ MAX_EXECUTION_TIME(50) */ 1"},
+		{"SELECT /*+ max_execution_time(50) */ 1", time.Second, "SELECT /*+ max_execution_time(50) */ 1"},
+		{"UPDATE foo SET a = 1", time.Second, "UPDATE foo SET a = 1"},
+		{"INSERT INTO foo SELECT * FROM bar", time.Second, "INSERT INTO foo SELECT * FROM bar"},
+		{"CALL SP_ListAll()", time.Second, "CALL SP_ListAll()"},
+		{"SEL", time.Second, "SEL"},
+	}
+
+	for _, tt := range tests {
+		if got := withMaxExecutionTimeHint(tt.query, tt.timeout); got != tt.want {
+			t.Errorf("withMaxExecutionTimeHint(%q, %v) = %q, want %q", tt.query, tt.timeout, got, tt.want)
+		}
+	}
+}
+
+func TestMaxExecutionTime(t *testing.T) {
+	cfg := NewDefaultConfig()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if got := maxExecutionTime(ctx, cfg, "SELECT 1"); got != "SELECT 1" {
+		t.Errorf("got %q without ServerSideTimeout", got)
+	}
+
+	if got := maxExecutionTime(WithQueryOptions(ctx, MaxExecutionTime(50*time.Millisecond)), cfg, "SELECT 1"); got != "SELECT /*+ MAX_EXECUTION_TIME(50) */ 1" {
+		t.Errorf("got %q with MaxExecutionTime option", got)
+	}
+
+	cfg.ServerSideTimeout = true
+	if got := maxExecutionTime(context.Background(), cfg, "SELECT 1"); got != "SELECT 1" {
+		t.Errorf("got %q without a deadline", got)
+	}
+
+	if got := maxExecutionTime(WithQueryOptions(ctx, MaxExecutionTime(0)), cfg, "SELECT 1"); got != "SELECT 1" {
+		t.Errorf("got %q with zero MaxExecutionTime option", got)
+	}
+
+	got := maxExecutionTime(ctx, cfg, "SELECT 1")
+	if ms := hintedMilliseconds(t, got); ms > 1000 || ms < 900 {
+		t.Errorf("got %q, want the time left until the deadline", got)
+	}
+}
+
+func TestMaxExecutionTimeExcludesQueueWait(t *testing.T) {
+	d := &fakeDriver{delay: 300 * time.Millisecond}
+	c := newFakeClient(t, d, func(cfg *Config) {
+		cfg.MaxOpenConns = 1
+		cfg.Timeout = 2 * time.Second
+		cfg.ServerSideTimeout = true
+		cfg.MaxQueueWait = time.Second
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Query(context.Background(), "SELECT 1"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var hints []int64
+	for _, query := range d.executed() {
+		if hintRegexp.MatchString(query) {
+			hints = append(hints, hintedMilliseconds(t, query))
+		}
+	}
+
+	if len(hints) != 2 {
+		t.Fatalf("got %d hinted queries, want 2", len(hints))
+	}
+	if hints[1] > 1800 {
+		t.Errorf("got hint %dms for a query that waited in the queue, want at most 1800ms", hints[1])
+	}
+}
+
+var hintRegexp = regexp.MustCompile(`MAX_EXECUTION_TIME\((\d+)\)`)
+
+func hintedMilliseconds(t *testing.T, query string) int64 {
+	t.Helper()
+
+	m := hintRegexp.FindStringSubmatch(query)
+	if m == nil {
+		t.Fatalf("no MAX_EXECUTION_TIME hint in %q", query)
+	}
+
+	ms, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ms
+}
@@ -0,0 +1,33 @@
+package mysql
+
+import (
+	"context"
+)
+
+// Tracer starts spans for calls made by Client and Transaction: Begin, every statement, Commit and Rollback.
+// It's set with Config.Tracer, see the mysqlotel package for an OpenTelemetry implementation.
+//
+type Tracer interface {
+	// StartSpan starts a span as a child of a span found in ctx. The returned context is passed down to the driver.
+	StartSpan(ctx context.Context, operation Operation, query string) (context.Context, Span)
+}
+
+// Span is a span started by Tracer. It's ended exactly once with the sample describing the call,
+// including calls rejected before reaching the database.
+//
+type Span interface {
+	End(sample *QueryStats)
+}
+
+type noopSpan struct{}
+
+func (noopSpan) End(*QueryStats) {}
+
+// startSpan starts a span with the tracer, it's a no-op if tracer is nil.
+func startSpan(ctx context.Context, tracer Tracer, operation Operation, query string) (context.Context, Span) {
+	if tracer == nil {
+		return ctx, noopSpan{}
+	}
+
+	return tracer.StartSpan(ctx, operation, query)
+}
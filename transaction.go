@@ -9,19 +9,39 @@ import (
 	"time"
 )
 
+// transactionSeq generates transaction ids, unique within the process.
+var transactionSeq uint64
+
 type Transaction struct {
-	tx *sql.Tx
-	s  *stats
+	tx   *sql.Tx
+	db   *sql.DB // endpoint the transaction was started on
+	f    *failover
+	s    *stats
+	id   uint64
+	role Role
 
 	config    *Config
 	r         *relay
+	tn        *tenants
+	b         *breaker
+	cm        *commenter
+	tenant    string
 	done      []chan error
 	mu        sync.RWMutex
 	startedAt time.Time
+	finished  int32
+}
+
+func newTransaction(tx *sql.Tx, db *sql.DB, f *failover, s *stats, config *Config, r *relay, tn *tenants, b *breaker, cm *commenter, tenant string, role Role) *Transaction {
+	id := atomic.AddUint64(&transactionSeq, 1)
+	return &Transaction{tx, db, f, s, id, role, config, r, tn, b, cm, tenant, make([]chan error, 0), sync.RWMutex{}, time.Now(), 0}
 }
 
-func newTransaction(tx *sql.Tx, s *stats, config *Config, r *relay) *Transaction {
-	return &Transaction{tx, s, config, r, make([]chan error, 0), sync.RWMutex{}, time.Now()}
+// ID returns the transaction id reported as TransactionID in QueryStats. It's generated by the client,
+// it's not the server side transaction id.
+//
+func (t *Transaction) ID() uint64 {
+	return t.id
 }
 
 func (t *Transaction) Call(ctx context.Context, procedure string, args ...interface{}) (*Results, error) {
@@ -48,26 +68,36 @@ func (t *Transaction) Query(ctx context.Context, query string, args ...interface
 	)
 
 	defer func(err *error) {
+		t.tn.count(t.tenant, *err)
+		t.b.done(ctx, false, *err)
+		t.failover(*err)
+
 		if *err == nil {
 			atomic.AddInt64(t.s.totalSuccessQueries, 1)
 		} else {
-			atomic.AddInt64(t.s.totalFailedQueries, 1)
-			logger.FromCtx(ctx).Tag("mysql").Error(*err, query)
+			t.s.failed(*err)
 		}
 
 	}(&err)
 
 	start := time.Now()
-	if t.config.Timeout > 0 {
-		ctx, cancel = context.WithTimeout(ctx, t.config.Timeout)
-		defer cancel()
-	}
-
-	rows, err = t.tx.QueryContext(ctx, query, args...)
+	sample := &QueryStats{Query: redactQuery(t.config, query), Operation: OperationQuery, Args: len(args), Attempts: 1, Role: t.role, TransactionID: t.id, StartedAt: start, statement: query, args: args}
+	ctx, span := startSpan(ctx, t.config.Tracer, OperationQuery, sample.Query)
+	defer func() {
+		t.s.sample(ctx, sample.finish(err))
+		span.End(sample)
+		logSample(ctx, t.config, sample)
+	}()
+
+	ctx, cancel = withTimeout(ctx, t.config)
+	defer cancel()
+
+	rows, err = t.tx.QueryContext(ctx, t.cm.comment(ctx, t.config, maxExecutionTime(ctx, t.config, query)), args...)
 	queryTime := time.Now().Sub(start)
-	t.s.sample(&QueryStats{query, queryTime, 0, 0})
+	sample.ExecutionTime = queryTime
 
 	if err != nil {
+		err = newQueryError(t.config, err, query, args, 1, 0, queryTime)
 		return nil, err
 	}
 
@@ -78,7 +108,7 @@ func (t *Transaction) Query(ctx context.Context, query string, args ...interface
 	}
 
 	results.QueryTime = queryTime
-	logger.FromCtx(ctx).Tag("mysql").Debug(query, queryTime, 0, 0)
+	sample.RowsReturned = int64(results.Count())
 	return results, nil
 }
 
@@ -90,26 +120,36 @@ func (t *Transaction) MultiQuery(ctx context.Context, query string, args ...inte
 	)
 
 	defer func(err *error) {
+		t.tn.count(t.tenant, *err)
+		t.b.done(ctx, false, *err)
+		t.failover(*err)
+
 		if *err == nil {
 			atomic.AddInt64(t.s.totalSuccessQueries, 1)
 		} else {
-			atomic.AddInt64(t.s.totalFailedQueries, 1)
-			logger.FromCtx(ctx).Tag("mysql").Error(*err, query)
+			t.s.failed(*err)
 		}
 
 	}(&err)
 
 	start := time.Now()
-	if t.config.Timeout > 0 {
-		ctx, cancel = context.WithTimeout(ctx, t.config.Timeout)
-		defer cancel()
-	}
-
-	rows, err = t.tx.QueryContext(ctx, query, args...)
+	sample := &QueryStats{Query: redactQuery(t.config, query), Operation: OperationQuery, Args: len(args), Attempts: 1, Role: t.role, TransactionID: t.id, StartedAt: start, statement: query, args: args}
+	ctx, span := startSpan(ctx, t.config.Tracer, OperationQuery, sample.Query)
+	defer func() {
+		t.s.sample(ctx, sample.finish(err))
+		span.End(sample)
+		logSample(ctx, t.config, sample)
+	}()
+
+	ctx, cancel = withTimeout(ctx, t.config)
+	defer cancel()
+
+	rows, err = t.tx.QueryContext(ctx, t.cm.comment(ctx, t.config, maxExecutionTime(ctx, t.config, query)), args...)
 	queryTime := time.Now().Sub(start)
-	t.s.sample(&QueryStats{query, queryTime, 0, 0})
+	sample.ExecutionTime = queryTime
 
 	if err != nil {
+		err = newQueryError(t.config, err, query, args, 1, 0, queryTime)
 		return nil, err
 	}
 
@@ -120,19 +160,19 @@ func (t *Transaction) MultiQuery(ctx context.Context, query string, args ...inte
 	}
 
 	for {
-		results, err := newResultsFromSqlRows(rows)
-		if err != nil {
+		var results *Results
+		if results, err = newResultsFromSqlRows(rows); err != nil {
 			return nil, err
 		}
 		results.QueryTime = queryTime
 		multiResults.Results = append(multiResults.Results, results)
+		sample.RowsReturned += int64(results.Count())
 
 		if rows.NextResultSet() == false {
 			break
 		}
 	}
 
-	logger.FromCtx(ctx).Tag("mysql").Debug(query, queryTime, 0, 0)
 	return multiResults, nil
 }
 
@@ -144,34 +184,43 @@ func (t *Transaction) Exec(ctx context.Context, query string, args ...interface{
 	)
 
 	defer func(err *error) {
+		t.tn.count(t.tenant, *err)
+		t.b.done(ctx, false, *err)
+		t.failover(*err)
+
 		if *err == nil {
 			atomic.AddInt64(t.s.totalSuccessQueries, 1)
 		} else {
-			atomic.AddInt64(t.s.totalFailedQueries, 1)
-			logger.FromCtx(ctx).Tag("mysql").Error(*err, query)
+			t.s.failed(*err)
 		}
 
 	}(&err)
 
 	start := time.Now()
-	if t.config.Timeout > 0 {
-		ctx, cancel = context.WithTimeout(ctx, t.config.Timeout)
-		defer cancel()
-	}
-
-	result, err = t.tx.ExecContext(ctx, query, args...)
+	sample := &QueryStats{Query: redactQuery(t.config, query), Operation: OperationExec, Args: len(args), Attempts: 1, Role: t.role, TransactionID: t.id, StartedAt: start, statement: query, args: args}
+	ctx, span := startSpan(ctx, t.config.Tracer, OperationExec, sample.Query)
+	defer func() {
+		t.s.sample(ctx, sample.finish(err))
+		span.End(sample)
+		logSample(ctx, t.config, sample)
+	}()
+
+	ctx, cancel = withTimeout(ctx, t.config)
+	defer cancel()
+
+	result, err = t.tx.ExecContext(ctx, t.cm.comment(ctx, t.config, query), args...)
 	queryTime := time.Now().Sub(start)
-	t.s.sample(&QueryStats{query, queryTime, 0, 0})
+	sample.ExecutionTime = queryTime
 
 	if err != nil {
+		err = newQueryError(t.config, err, query, args, 1, 0, queryTime)
 		return nil, err
 	}
 
 	meta := newMeta(result)
 	meta.QueryTime = queryTime
-	t.s.sample(&QueryStats{query, queryTime, 0, 0})
+	sample.RowsAffected = meta.RowsAffected
 
-	logger.FromCtx(ctx).Tag("mysql").Debug(query, meta.QueryTime)
 	return meta, nil
 }
 
@@ -180,27 +229,35 @@ func (t *Transaction) Commit(ctx context.Context) error {
 		return nil
 	}
 
-	defer atomic.AddInt64(t.s.inProgressQueries, -1)
-	defer t.r.end()
-
 	var err error
 
 	defer func(err *error) {
+		if t.finish() {
+			t.tn.end(t.tenant, *err)
+		} else {
+			t.tn.count(t.tenant, *err)
+		}
+		t.b.done(ctx, false, *err)
+		t.failover(*err)
+
 		if *err == nil {
 			atomic.AddInt64(t.s.totalSuccessQueries, 1)
 		} else {
-			atomic.AddInt64(t.s.totalFailedQueries, 1)
-			logger.FromCtx(ctx).Tag("mysql").Error(*err, "COMMIT")
+			t.s.failed(*err)
 		}
 
 	}(&err)
 
 	start := time.Now()
+	ctx, span := startSpan(ctx, t.config.Tracer, OperationCommit, "COMMIT")
 	err = t.tx.Commit()
 	queryTime := time.Now().Sub(start)
 	txTime := time.Now().Sub(t.startedAt)
-	t.s.sample(&QueryStats{"COMMIT", queryTime, 0, txTime})
-	logger.FromCtx(ctx).Tag("mysql").Debug("COMMIT", queryTime, 0, txTime)
+	err = newQueryError(t.config, err, "COMMIT", nil, 1, 0, queryTime)
+	sample := &QueryStats{Query: "COMMIT", Operation: OperationCommit, ExecutionTime: queryTime, TransactionTime: txTime, Attempts: 1, Role: t.role, TransactionID: t.id, StartedAt: start}
+	t.s.sample(ctx, sample.finish(err))
+	span.End(sample)
+	logSample(ctx, t.config, sample)
 	t.close(err)
 	return err
 }
@@ -210,27 +267,35 @@ func (t *Transaction) Rollback(ctx context.Context) error {
 		return nil
 	}
 
-	defer atomic.AddInt64(t.s.inProgressQueries, -1)
-	defer t.r.end()
-
 	var err error
 
 	defer func(err *error) {
+		if t.finish() {
+			t.tn.end(t.tenant, *err)
+		} else {
+			t.tn.count(t.tenant, *err)
+		}
+		t.b.done(ctx, false, *err)
+		t.failover(*err)
+
 		if *err == nil {
 			atomic.AddInt64(t.s.totalSuccessQueries, 1)
 		} else {
-			atomic.AddInt64(t.s.totalFailedQueries, 1)
-			logger.FromCtx(ctx).Tag("mysql").Error(*err, "ROLLBACK")
+			t.s.failed(*err)
 		}
 	}(&err)
 
 	start := time.Now()
+	ctx, span := startSpan(ctx, t.config.Tracer, OperationRollback, "ROLLBACK")
 	err = t.tx.Rollback()
 	queryTime := time.Now().Sub(start)
 	txTime := time.Now().Sub(t.startedAt)
+	err = newQueryError(t.config, err, "ROLLBACK", nil, 1, 0, queryTime)
 
-	t.s.sample(&QueryStats{"ROLLBACK", queryTime, 0, txTime})
-	logger.FromCtx(ctx).Tag("mysql").Debug("ROLLBACK", queryTime, 0, txTime)
+	sample := &QueryStats{Query: "ROLLBACK", Operation: OperationRollback, ExecutionTime: queryTime, TransactionTime: txTime, Attempts: 1, Role: t.role, TransactionID: t.id, StartedAt: start}
+	t.s.sample(ctx, sample.finish(err))
+	span.End(sample)
+	logSample(ctx, t.config, sample)
 	if err != nil {
 		t.close(err)
 	} else {
@@ -246,6 +311,18 @@ func (t *Transaction) WithContext(ctx context.Context) context.Context {
 	return context.WithValue(ctx, "tx", t)
 }
 
+// finish releases the slot held by the transaction, it returns false if the slot was already released
+// by a previous Commit or Rollback.
+func (t *Transaction) finish() bool {
+	if !atomic.CompareAndSwapInt32(&t.finished, 0, 1) {
+		return false
+	}
+
+	atomic.AddInt64(t.s.inProgressQueries, -1)
+	t.r.end()
+	return true
+}
+
 func (t *Transaction) close(err error) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()